@@ -14,6 +14,8 @@ import (
 	"subscription_service/internal/config"
 	"subscription_service/internal/database"
 	httpapi "subscription_service/internal/http"
+	"subscription_service/internal/notify"
+	"subscription_service/internal/repo/memory"
 	"subscription_service/internal/repo/postgres"
 	"subscription_service/internal/service"
 	"syscall"
@@ -34,18 +36,53 @@ func main() {
 		log.Fatalf("config error: %v", err)
 	}
 
-	db, err := database.NewPostgres(&cfg)
-	if err != nil {
-		log.Fatalf("db error: %v", err)
+	var (
+		repo        service.SubscriptionRepository
+		rateRepo    service.ExchangeRateRepository
+		idemRepo    service.IdempotencyRepository
+		catalogRepo service.CatalogRepository
+		budgetRepo  service.BudgetRepository
+	)
+	switch cfg.Storage {
+	case "memory":
+		log.Println("using in-memory storage")
+		memRates := memory.NewExchangeRateRepo()
+		rateRepo = memRates
+		memRepo := memory.NewSubscriptionRepo(memRates)
+		repo = memRepo
+		idemRepo = memory.NewIdempotencyRepo()
+		catalogRepo = memory.NewCatalogRepo(memRepo)
+		budgetRepo = memory.NewBudgetRepo()
+	default:
+		db, err := database.NewPostgres(&cfg)
+		if err != nil {
+			log.Fatalf("db error: %v", err)
+		}
+		defer db.Close()
+
+		repo = postgres.NewSubscriptionRepo(db)
+		rateRepo = postgres.NewExchangeRateRepo(db)
+		idemRepo = postgres.NewIdempotencyRepo(db)
+		catalogRepo = postgres.NewCatalogRepo(db)
+		budgetRepo = postgres.NewBudgetRepo(db)
 	}
-	defer db.Close()
 
-	repo := postgres.NewSubscriptionRepo(db)
-	svc := service.NewSubscriptionService(repo)
-	h := httpapi.NewHandler(svc)
+	svc := service.NewSubscriptionService(repo, catalogRepo, budgetRepo)
+	rates := service.NewExchangeRateService(rateRepo)
+	idem := service.NewIdempotencyService(idemRepo, cfg.IdempotencyTTL)
+	catalog := service.NewCatalogService(catalogRepo)
+	budgets := service.NewBudgetService(budgetRepo, svc, notify.Log{})
+	h := httpapi.NewHandler(svc, rates, idem, catalog, budgets)
 
 	router := httpapi.NewRouter(h)
 
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	go runPurger(bgCtx, svc, cfg.PurgeInterval, cfg.SoftDeleteRetention)
+	go runIdempotencyCleanup(bgCtx, idem, time.Hour)
+	go runBudgetCheck(bgCtx, budgets, cfg.BudgetCheckInterval)
+
 	addr := ":" + cfg.HTTPPort
 
 	srv := &http.Server{
@@ -67,6 +104,7 @@ func main() {
 
 	<-stop
 	log.Println("shutdown signal received...")
+	stopBackground()
 
 	// Даём активным запросам завершиться и мягко закрываем наше приложение
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -78,3 +116,73 @@ func main() {
 
 	log.Println("server stopped gracefully")
 }
+
+// runPurger периодически физически удаляет подписки, удалённые дольше retention.
+func runPurger(ctx context.Context, svc *service.SubscriptionService, interval, retention time.Duration) {
+	if interval <= 0 || retention <= 0 {
+		log.Println("purge of deleted subscriptions is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := svc.PurgeDeleted(ctx, retention)
+			if err != nil {
+				log.Printf("purge error: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("purged %d deleted subscriptions", n)
+			}
+		}
+	}
+}
+
+// runIdempotencyCleanup периодически удаляет истёкшие ключи идемпотентности.
+func runIdempotencyCleanup(ctx context.Context, idem *service.IdempotencyService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := idem.PurgeExpired(ctx); err != nil {
+				log.Printf("idempotency cleanup error: %v", err)
+			}
+		}
+	}
+}
+
+// runBudgetCheck периодически сверяет бюджеты с расходами текущего месяца и рассылает уведомления о превышении.
+func runBudgetCheck(ctx context.Context, budgets *service.BudgetService, interval time.Duration) {
+	if interval <= 0 {
+		log.Println("budget check is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := budgets.Evaluate(ctx)
+			if err != nil {
+				log.Printf("budget check error: %v", err)
+			}
+			if n > 0 {
+				log.Printf("recorded %d budget alerts", n)
+			}
+		}
+	}
+}
@@ -9,6 +9,9 @@ type Config struct {
 	HTTPPort string `env:"HTTP_PORT" default:"8080"`
 	DBURL    string `env:"DB_URL" default:""`
 
+	// Storage: "postgres" или "memory" (без базы, данные живут до рестарта)
+	Storage string `env:"STORAGE" default:"postgres"`
+
 	PostgresHost     string `env:"POSTGRES_HOST" default:"localhost"`
 	PostgresPort     string `env:"POSTGRES_PORT" default:"5432"`
 	PostgresUser     string `env:"POSTGRES_USER" default:"postgres"`
@@ -18,6 +21,17 @@ type Config struct {
 	MaxOpenConns int           `env:"POSTGRES_MAX_OPEN_CONNECTIONS" default:"25"`
 	MaxIdleConns int           `env:"POSTGRES_MAX_IDLE_CONNECTIONS" default:"25"`
 	ConnMaxLife  time.Duration `env:"POSTGRES_CONNECTION_MAX_LIFETIME" default:"5m"`
+
+	// Удалённые подписки физически удаляются через SoftDeleteRetention.
+	// Нулевое значение любого из параметров отключает очистку.
+	SoftDeleteRetention time.Duration `env:"SOFT_DELETE_RETENTION" default:"720h"`
+	PurgeInterval       time.Duration `env:"PURGE_INTERVAL" default:"1h"`
+
+	// Сколько хранится ответ на POST с Idempotency-Key (0 — 24h).
+	IdempotencyTTL time.Duration `env:"IDEMPOTENCY_TTL" default:"24h"`
+
+	// Как часто бюджеты сверяются с расходами текущего месяца (0 — не проверять).
+	BudgetCheckInterval time.Duration `env:"BUDGET_CHECK_INTERVAL" default:"1h"`
 }
 
 func (c *Config) BuildDBURL() string {
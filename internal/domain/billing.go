@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// BillingPeriod describes how often a subscription charges its Price.
+type BillingPeriod string
+
+const (
+	BillingWeekly    BillingPeriod = "weekly"
+	BillingMonthly   BillingPeriod = "monthly"
+	BillingQuarterly BillingPeriod = "quarterly"
+	BillingYearly    BillingPeriod = "yearly"
+)
+
+// ParseBillingPeriod validates a billing period; empty string means monthly.
+func ParseBillingPeriod(s string) (BillingPeriod, error) {
+	switch p := BillingPeriod(s); p {
+	case "":
+		return BillingMonthly, nil
+	case BillingWeekly, BillingMonthly, BillingQuarterly, BillingYearly:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid billing_period %q (expected weekly, monthly, quarterly, yearly)", s)
+	}
+}
+
+// ChargesInMonth returns how many times a subscription billed from start through end
+// is charged during month m. The caller checks that m is within the subscription range.
+// Must stay in sync with the billing CASE in postgres.SubscriptionRepo.
+func (p BillingPeriod) ChargesInMonth(start time.Time, end *time.Time, m time.Time) int64 {
+	m = MonthStartUTC(m)
+
+	switch p {
+	case BillingWeekly:
+		// списания в дни start + 7k, считаем те, что попали в [m, следующий месяц) и не позже end
+		next := NextMonthStartUTC(m)
+		if end != nil && end.AddDate(0, 0, 1).Before(next) {
+			next = end.AddDate(0, 0, 1)
+		}
+		return weeklyChargesBefore(start, next) - weeklyChargesBefore(start, m)
+	case BillingQuarterly:
+		if MonthsBetween(start, m)%3 == 0 {
+			return 1
+		}
+		return 0
+	case BillingYearly:
+		if MonthsBetween(start, m)%12 == 0 {
+			return 1
+		}
+		return 0
+	default:
+		return 1
+	}
+}
+
+// ChargeDate returns the date of the n-th charge (n >= 0) of a subscription billed from start.
+// Monthly, quarterly and yearly charges fall on the day of month of start, clamped to the last day
+// of shorter months, so they stay in the months counted by ChargesInMonth.
+func (p BillingPeriod) ChargeDate(start time.Time, n int) time.Time {
+	var step int
+	switch p {
+	case BillingWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case BillingQuarterly:
+		step = 3
+	case BillingYearly:
+		step = 12
+	default:
+		step = 1
+	}
+	m := MonthStartUTC(start).AddDate(0, step*n, 0)
+	day := min(start.Day(), NextMonthStartUTC(m).AddDate(0, 0, -1).Day())
+	return time.Date(m.Year(), m.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+// MonthsBetween returns the number of whole calendar months from a to b.
+func MonthsBetween(a, b time.Time) int {
+	return (b.Year()-a.Year())*12 + int(b.Month()) - int(a.Month())
+}
+
+// weeklyChargesBefore counts weekly charge dates in [start, d).
+func weeklyChargesBefore(start, d time.Time) int64 {
+	days := int64(d.Sub(start).Hours() / 24)
+	if days <= 0 {
+		return 0
+	}
+	return (days + 6) / 7
+}
+
+// Proration controls how months the subscription covers only partly are charged.
+type Proration string
+
+const (
+	ProrationNone  Proration = ""      // a month is charged in full if the subscription is in effect on any day of it
+	ProrationDaily Proration = "daily" // monthly charges are scaled by the share of days covered, see Subscription.CoveredFraction
+)
+
+// ParseProration validates a proration mode; empty string means no proration.
+func ParseProration(s string) (Proration, error) {
+	switch p := Proration(s); p {
+	case ProrationNone, ProrationDaily:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid proration %q (expected daily)", s)
+	}
+}
+
+// Factor is the multiplier of a charge of s in month m under proration p.
+// Weekly charges are counted by date anyway; quarterly and yearly ones are not split.
+func (p Proration) Factor(s Subscription, m time.Time) float64 {
+	if p != ProrationDaily {
+		return 1
+	}
+	switch s.BillingPeriod {
+	case BillingWeekly, BillingQuarterly, BillingYearly:
+		return 1
+	default:
+		return s.CoveredFraction(m)
+	}
+}
@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// Budget is a monthly spending limit of a user, checked against the same monthly cost as TotalCost.
+// With Tag set only subscriptions having that tag count.
+type Budget struct {
+	ID        int64     `db:"id"`
+	UserID    string    `db:"user_id"` // UUID as string
+	Amount    int64     `db:"amount"`  // limit per month in Currency
+	Currency  string    `db:"currency"`
+	Tag       *string   `db:"tag"` // normalized, see NormalizeTags
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+type BudgetDTO struct {
+	ID       int64   `json:"id"`
+	UserID   string  `json:"user_id"`
+	Amount   int64   `json:"amount"`
+	Currency string  `json:"currency"`
+	Tag      *string `json:"tag,omitempty"`
+}
+
+func ToBudgetDTO(b Budget) BudgetDTO {
+	return BudgetDTO{
+		ID:       b.ID,
+		UserID:   b.UserID,
+		Amount:   b.Amount,
+		Currency: b.Currency,
+		Tag:      b.Tag,
+	}
+}
+
+// Filter returns the total cost filter of the user's spend counted against the budget from one month to another.
+func (b Budget) Filter(from, to time.Time) TotalFilter {
+	f := TotalFilter{
+		UserID:   &b.UserID,
+		From:     MonthStartUTC(from),
+		To:       MonthStartUTC(to),
+		Currency: b.Currency,
+	}
+	if b.Tag != nil {
+		f.Tags = []string{*b.Tag}
+	}
+	return f
+}
+
+// Counts reports whether subscription s is counted against the budget:
+// the user has a share of it and it has the budget tag.
+func (b Budget) Counts(s Subscription) bool {
+	if b.Tag != nil && !slices.Contains(s.Tags, *b.Tag) {
+		return false
+	}
+	return slices.ContainsFunc(s.Shares(), func(sh MemberShare) bool {
+		return sh.UserID == b.UserID
+	})
+}
+
+// BudgetOverrun is a month in which the spend went over a budget.
+type BudgetOverrun struct {
+	BudgetID int64     `db:"budget_id"`
+	UserID   string    `db:"user_id"`
+	Month    time.Time `db:"month"`        // month start (UTC)
+	Limit    int64     `db:"limit_amount"` // Budget.Amount at the time of the check
+	Spent    int64     `db:"spent"`        // net cost of the month, in Currency
+	Currency string    `db:"currency"`
+}
+
+type BudgetOverrunDTO struct {
+	BudgetID int64  `json:"budget_id"`
+	UserID   string `json:"user_id"`
+	Month    string `json:"month"` // MM-YYYY
+	Limit    int64  `json:"limit"`
+	Spent    int64  `json:"spent"`
+	Currency string `json:"currency"`
+}
+
+func ToBudgetOverrunDTO(o BudgetOverrun) BudgetOverrunDTO {
+	return BudgetOverrunDTO{
+		BudgetID: o.BudgetID,
+		UserID:   o.UserID,
+		Month:    FormatMonthYear(o.Month),
+		Limit:    o.Limit,
+		Spent:    o.Spent,
+		Currency: o.Currency,
+	}
+}
+
+// BudgetAlert is a recorded overrun, at most one per budget and month.
+type BudgetAlert struct {
+	ID int64 `db:"id"`
+	BudgetOverrun
+	CreatedAt  time.Time  `db:"created_at"`
+	NotifiedAt *time.Time `db:"notified_at"` // NULL until delivered by the notifier
+}
+
+type BudgetAlertDTO struct {
+	ID int64 `json:"id"`
+	BudgetOverrunDTO
+	CreatedAt  string  `json:"created_at"`            // RFC 3339
+	NotifiedAt *string `json:"notified_at,omitempty"` // RFC 3339
+}
+
+func ToBudgetAlertDTO(a BudgetAlert) BudgetAlertDTO {
+	var notified *string
+	if a.NotifiedAt != nil {
+		v := a.NotifiedAt.UTC().Format(time.RFC3339)
+		notified = &v
+	}
+	return BudgetAlertDTO{
+		ID:               a.ID,
+		BudgetOverrunDTO: ToBudgetOverrunDTO(a.BudgetOverrun),
+		CreatedAt:        a.CreatedAt.UTC().Format(time.RFC3339),
+		NotifiedAt:       notified,
+	}
+}
+
+type BudgetAlertFilter struct {
+	UserID  string // empty = all users
+	Pending bool   // only alerts not delivered yet
+
+	Limit  int
+	Offset int
+}
+
+// SubscriptionChangeDTO is a created or updated subscription with the budgets it pushes over.
+type SubscriptionChangeDTO struct {
+	SubscriptionDTO
+	Warnings []BudgetOverrunDTO `json:"warnings,omitempty"`
+	// WarningsUnavailable is set when budgets could not be checked, e.g. for a missing exchange rate.
+	WarningsUnavailable bool `json:"warnings_unavailable,omitempty"`
+}
+
+func ToSubscriptionChangeDTO(s Subscription, warnings []BudgetOverrun) SubscriptionChangeDTO {
+	out := SubscriptionChangeDTO{SubscriptionDTO: ToDTO(s)}
+	for _, w := range warnings {
+		out.Warnings = append(out.Warnings, ToBudgetOverrunDTO(w))
+	}
+	return out
+}
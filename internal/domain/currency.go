@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BaseCurrency is the currency exchange rates are quoted against.
+const BaseCurrency = "RUB"
+
+// ParseCurrency normalizes an ISO 4217 code; empty string means BaseCurrency.
+func ParseCurrency(s string) (string, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return BaseCurrency, nil
+	}
+	if len(s) != 3 {
+		return "", fmt.Errorf("invalid currency %q (expected ISO 4217 code)", s)
+	}
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return "", fmt.Errorf("invalid currency %q (expected ISO 4217 code)", s)
+		}
+	}
+	return s, nil
+}
+
+// ExchangeRate is the price of one unit of Currency in BaseCurrency during Month.
+type ExchangeRate struct {
+	Month    time.Time `db:"month"` // month start (UTC)
+	Currency string    `db:"currency"`
+	Rate     float64   `db:"rate"`
+}
+
+type ExchangeRateDTO struct {
+	Month    string  `json:"month"` // MM-YYYY
+	Currency string  `json:"currency"`
+	Rate     float64 `json:"rate"`
+}
+
+func ToExchangeRateDTO(r ExchangeRate) ExchangeRateDTO {
+	return ExchangeRateDTO{
+		Month:    FormatMonthYear(r.Month),
+		Currency: r.Currency,
+		Rate:     r.Rate,
+	}
+}
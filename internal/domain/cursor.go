@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ListCursor points at the last row of a page; the next page starts right after it.
+// Clients get it as an opaque string and must not build it themselves.
+type ListCursor struct {
+	Sort string `json:"s,omitempty"` // ListSort.String() of the page the cursor was issued for
+	Key  string `json:"k,omitempty"` // sort-key value of the last row, see ListSort.Key
+	ID   int64  `json:"id"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func EncodeCursor(c ListCursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(s string) (ListCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return ListCursor{}, ErrInvalidCursor
+	}
+	var c ListCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID <= 0 {
+		return ListCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// SubscriptionPage is one page of List in keyset mode.
+type SubscriptionPage struct {
+	Items      []Subscription
+	NextCursor *string // nil on the last page
+	TotalCount *int64  // only when requested
+}
+
+type SubscriptionPageDTO struct {
+	Items      []SubscriptionDTO `json:"items"`
+	NextCursor *string           `json:"next_cursor"`
+	TotalCount *int64            `json:"total_count,omitempty"`
+}
+
+func ToSubscriptionPageDTO(p SubscriptionPage) SubscriptionPageDTO {
+	items := make([]SubscriptionDTO, 0, len(p.Items))
+	for _, s := range p.Items {
+		items = append(items, ToDTO(s))
+	}
+	return SubscriptionPageDTO{Items: items, NextCursor: p.NextCursor, TotalCount: p.TotalCount}
+}
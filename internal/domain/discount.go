@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// DiscountKind is how a discount reduces the monthly cost.
+type DiscountKind string
+
+const (
+	DiscountPercent DiscountKind = "percent" // Value percent off, 1..100
+	DiscountFixed   DiscountKind = "fixed"   // Value off per month, in the subscription currency
+)
+
+// ParseDiscountKind validates a discount kind.
+func ParseDiscountKind(s string) (DiscountKind, error) {
+	switch k := DiscountKind(s); k {
+	case DiscountPercent, DiscountFixed:
+		return k, nil
+	default:
+		return "", fmt.Errorf("invalid discount kind %q (expected percent or fixed)", s)
+	}
+}
+
+// Discount reduces the cost of a subscription in months From through Until inclusive.
+type Discount struct {
+	ID             int64        `db:"id"`
+	SubscriptionID int64        `db:"subscription_id"`
+	Kind           DiscountKind `db:"kind"`
+	Value          int64        `db:"value"`
+	Code           *string      `db:"code"` // promo code the discount came from, informational
+	From           time.Time    `db:"from_month"`
+	Until          *time.Time   `db:"until_month"` // last discounted month, NULL = no end
+	CreatedAt      time.Time    `db:"created_at"`
+}
+
+type DiscountDTO struct {
+	ID    int64   `json:"id"`
+	Kind  string  `json:"kind"`
+	Value int64   `json:"value"`
+	Code  *string `json:"code,omitempty"`
+	From  string  `json:"from"`            // MM-YYYY
+	Until *string `json:"until,omitempty"` // MM-YYYY
+}
+
+func ToDiscountDTO(d Discount) DiscountDTO {
+	dto := DiscountDTO{
+		ID:    d.ID,
+		Kind:  string(d.Kind),
+		Value: d.Value,
+		Code:  d.Code,
+		From:  FormatMonthYear(d.From),
+	}
+	if d.Until != nil {
+		v := FormatMonthYear(*d.Until)
+		dto.Until = &v
+	}
+	return dto
+}
+
+// Covers reports whether the discount applies in month m.
+func (d Discount) Covers(m time.Time) bool {
+	m = MonthStartUTC(m)
+	return !d.From.After(m) && (d.Until == nil || !d.Until.Before(m))
+}
+
+// DiscountAt returns how much of gross, the cost of a subscription in month m, is taken off by discounts:
+// percentages of all discounts in effect add up, fixed amounts are subtracted on top,
+// and the discount never exceeds gross.
+// Must stay in sync with the discount expression in postgres.buildChargesCTE.
+func DiscountAt(discounts []Discount, m time.Time, gross float64) float64 {
+	var percent, fixed int64
+	for _, d := range discounts {
+		if !d.Covers(m) {
+			continue
+		}
+		switch d.Kind {
+		case DiscountPercent:
+			percent += d.Value
+		case DiscountFixed:
+			fixed += d.Value
+		}
+	}
+	return min(gross, gross*float64(percent)/100+float64(fixed))
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventAction is the kind of change recorded in the audit log.
+type EventAction string
+
+const (
+	EventCreate  EventAction = "create"
+	EventUpdate  EventAction = "update"
+	EventDelete  EventAction = "delete" // soft delete
+	EventRestore EventAction = "restore"
+	EventPurge   EventAction = "purge" // hard delete after retention
+	EventPause   EventAction = "pause"
+	EventResume  EventAction = "resume"
+)
+
+// SubscriptionEvent is an audit log record. Before/After hold SubscriptionDTO snapshots.
+type SubscriptionEvent struct {
+	ID             int64            `db:"id"`
+	SubscriptionID int64            `db:"subscription_id"`
+	Action         EventAction      `db:"action"`
+	Before         *json.RawMessage `db:"before"`
+	After          *json.RawMessage `db:"after"`
+	RequestID      *string          `db:"request_id"`
+	Actor          *string          `db:"actor"`
+	CreatedAt      time.Time        `db:"created_at"`
+}
+
+type SubscriptionEventDTO struct {
+	ID             int64            `json:"id"`
+	SubscriptionID int64            `json:"subscription_id"`
+	Action         string           `json:"action"`
+	Before         *json.RawMessage `json:"before"`
+	After          *json.RawMessage `json:"after"`
+	RequestID      *string          `json:"request_id,omitempty"`
+	Actor          *string          `json:"actor,omitempty"`
+	CreatedAt      string           `json:"created_at"` // RFC 3339
+}
+
+func ToEventDTO(e SubscriptionEvent) SubscriptionEventDTO {
+	return SubscriptionEventDTO{
+		ID:             e.ID,
+		SubscriptionID: e.SubscriptionID,
+		Action:         string(e.Action),
+		Before:         e.Before,
+		After:          e.After,
+		RequestID:      e.RequestID,
+		Actor:          e.Actor,
+		CreatedAt:      e.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// Snapshot serializes a subscription for the audit log; nil stays nil.
+func Snapshot(s *Subscription) (*json.RawMessage, error) {
+	if s == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(ToDTO(*s))
+	if err != nil {
+		return nil, err
+	}
+	raw := json.RawMessage(b)
+	return &raw, nil
+}
@@ -2,26 +2,110 @@ package domain
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
 type ListFilter struct {
-	UserID      *string
-	ServiceName *string
+	UserIDs           []string // payer or member, any of; empty = all users
+	ServiceName       *string  // exact match
+	ServiceNamePrefix *string  // case-insensitive prefix
+	Tags              []string // any of, normalized
 
 	From *time.Time // optional, month start
 	To   *time.Time // optional, month start (inclusive by month)
 
+	PriceMin *int64 // inclusive
+	PriceMax *int64 // inclusive
+
+	Status   ListStatus // optional, relative to StatusAt
+	StatusAt time.Time  // month start
+
+	IncludeDeleted bool
+
+	Sort ListSort
+
 	Limit  int
 	Offset int
+
+	// After switches List to keyset mode: rows following the cursor, Offset is ignored.
+	After *ListCursor
 }
 
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 200
+)
+
+const (
+	DefaultForecastMonths = 12
+	MaxForecastMonths     = 60
+)
+
 type TotalFilter struct {
-	UserID      *string
+	UserID      *string // counts only this user's share of shared subscriptions
 	ServiceName *string
+	Tags        []string // any of, normalized
 
 	From time.Time // month start (UTC)
 	To   time.Time // month start (UTC), inclusive by month
+
+	Currency  string    // target currency, empty = BaseCurrency
+	Proration Proration // how partly covered months are charged
+	// LatestRates converts with the latest rate loaded up to the month instead of the rate of that month.
+	// Forecasts need it: rates of future months are not known yet.
+	LatestRates bool
+
+	GroupBy []GroupByField // optional, used by grouped totals only
+}
+
+// TargetCurrency returns the currency totals are converted to.
+func (f TotalFilter) TargetCurrency() string {
+	if f.Currency == "" {
+		return BaseCurrency
+	}
+	return f.Currency
+}
+
+// GroupByField is a dimension the total cost can be split by.
+type GroupByField string
+
+const (
+	GroupByService GroupByField = "service"
+	GroupByUser    GroupByField = "user"
+	// GroupByTag puts a subscription into the group of every tag it has,
+	// so the sum over tag groups can exceed the total.
+	GroupByTag GroupByField = "tag"
+)
+
+// ParseGroupBy parses a comma separated group_by value, e.g. "service,user" or "tag".
+func ParseGroupBy(s string) ([]GroupByField, error) {
+	var out []GroupByField
+	seen := make(map[GroupByField]bool)
+	for _, part := range strings.Split(s, ",") {
+		g := GroupByField(strings.TrimSpace(part))
+		switch g {
+		case GroupByService, GroupByUser, GroupByTag:
+		default:
+			return nil, fmt.Errorf("invalid group_by %q (expected service, user, tag)", part)
+		}
+		if seen[g] {
+			continue
+		}
+		seen[g] = true
+		out = append(out, g)
+	}
+	return out, nil
+}
+
+// Groups reports whether the filter groups by g.
+func (f TotalFilter) Groups(g GroupByField) bool {
+	for _, v := range f.GroupBy {
+		if v == g {
+			return true
+		}
+	}
+	return false
 }
 
 func (f TotalFilter) Validate() error {
@@ -39,3 +123,17 @@ func (f TotalFilter) Validate() error {
 func (f TotalFilter) ToExclusive() time.Time {
 	return NextMonthStartUTC(f.To)
 }
+
+type ExchangeRateFilter struct {
+	Currency *string
+
+	From *time.Time // optional, month start
+	To   *time.Time // optional, month start (inclusive by month)
+}
+
+type EventFilter struct {
+	SubscriptionID int64
+
+	Limit  int
+	Offset int
+}
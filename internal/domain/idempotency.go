@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// IdempotencyRecord is a stored outcome of a request sent with an Idempotency-Key header.
+type IdempotencyRecord struct {
+	Key         string            `db:"key"`
+	RequestHash string            `db:"request_hash"`
+	StatusCode  *int              `db:"status_code"` // NULL — запрос ещё выполняется
+	Headers     map[string]string `db:"-"`           // заголовки ответа, которые повторяются вместе с телом (ETag)
+	Response    []byte            `db:"response"`
+	// ExpiresAt — до завершения запроса короткая аренда ключа, после — срок хранения ответа.
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+// Completed reports whether the original request has finished and its response can be replayed.
+func (r IdempotencyRecord) Completed() bool {
+	return r.StatusCode != nil
+}
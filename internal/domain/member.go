@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const MaxSubscriptionMembers = 50
+
+// SubscriptionMember is a user sharing the cost of a subscription.
+// Each member pays Weight / (sum of weights) of every charge.
+type SubscriptionMember struct {
+	UserID string `json:"user_id"` // UUID as string
+	Weight int    `json:"weight"`  // > 0
+}
+
+// MemberShare is the fraction of a subscription's cost attributed to one user.
+type MemberShare struct {
+	UserID string
+	Share  float64
+}
+
+// Shares splits the subscription between its members by weight.
+// A subscription without members belongs entirely to UserID, who pays for it;
+// with members the payer gets a share only if listed among them.
+func (s Subscription) Shares() []MemberShare {
+	if len(s.Members) == 0 {
+		return []MemberShare{{UserID: s.UserID, Share: 1}}
+	}
+
+	var total int
+	for _, m := range s.Members {
+		total += m.Weight
+	}
+	out := make([]MemberShare, 0, len(s.Members))
+	for _, m := range s.Members {
+		out = append(out, MemberShare{UserID: m.UserID, Share: float64(m.Weight) / float64(total)})
+	}
+	return out
+}
+
+// HasUser reports whether userID pays for the subscription or is one of its members.
+func (s Subscription) HasUser(userID string) bool {
+	return s.UserID == userID || slices.ContainsFunc(s.Members, func(m SubscriptionMember) bool {
+		return m.UserID == userID
+	})
+}
+
+// ParseUserID validates a user id and returns it in canonical UUID form,
+// the same form Postgres stores it in.
+func ParseUserID(s string) (string, error) {
+	id, err := uuid.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return "", fmt.Errorf("invalid user_id %q (expected UUID)", s)
+	}
+	return id.String(), nil
+}
+
+// NormalizeMembers validates members, defaults a zero weight to 1 and sorts them by user id.
+func NormalizeMembers(members []SubscriptionMember) ([]SubscriptionMember, error) {
+	if len(members) > MaxSubscriptionMembers {
+		return nil, fmt.Errorf("too many members (max %d)", MaxSubscriptionMembers)
+	}
+
+	out := make([]SubscriptionMember, 0, len(members))
+	seen := make(map[string]bool, len(members))
+	for _, m := range members {
+		id, err := uuid.Parse(strings.TrimSpace(m.UserID))
+		if err != nil {
+			return nil, fmt.Errorf("invalid member user_id %q (expected UUID)", m.UserID)
+		}
+		m.UserID = id.String()
+		if seen[m.UserID] {
+			return nil, fmt.Errorf("duplicate member %s", m.UserID)
+		}
+		seen[m.UserID] = true
+
+		switch {
+		case m.Weight == 0:
+			m.Weight = 1
+		case m.Weight < 0:
+			return nil, fmt.Errorf("member weight must be > 0")
+		}
+		out = append(out, m)
+	}
+	slices.SortFunc(out, func(a, b SubscriptionMember) int { return cmp.Compare(a.UserID, b.UserID) })
+	return out, nil
+}
@@ -0,0 +1,72 @@
+package domain
+
+import "time"
+
+// Overlaps reports whether s and o are in effect on at least one common day.
+func (s Subscription) Overlaps(o Subscription) bool {
+	return (o.EndDate == nil || !s.StartDate.After(*o.EndDate)) &&
+		(s.EndDate == nil || !o.StartDate.After(*s.EndDate))
+}
+
+// Duplicates reports whether s and o look like the user paying twice:
+// the same payer and service, in effect on the same days.
+func (s Subscription) Duplicates(o Subscription) bool {
+	return s.ID != o.ID && s.UserID == o.UserID && s.ServiceName == o.ServiceName && s.Overlaps(o)
+}
+
+// SubscriptionOverlap is a pair of subscriptions of one user to one service in effect on the same days.
+type SubscriptionOverlap struct {
+	UserID      string     `db:"user_id"`
+	ServiceName string     `db:"service_name"`
+	FirstID     int64      `db:"first_id"` // the older subscription, FirstID < SecondID
+	SecondID    int64      `db:"second_id"`
+	From        time.Time  `db:"from_date"` // first common day
+	To          *time.Time `db:"to_date"`   // last common day, NULL if both have no end
+}
+
+// NewSubscriptionOverlap returns the overlap of two subscriptions; the caller checks that they overlap.
+func NewSubscriptionOverlap(a, b Subscription) SubscriptionOverlap {
+	if b.ID < a.ID {
+		a, b = b, a
+	}
+	o := SubscriptionOverlap{UserID: a.UserID, ServiceName: a.ServiceName, FirstID: a.ID, SecondID: b.ID, From: a.StartDate}
+	if b.StartDate.After(o.From) {
+		o.From = b.StartDate
+	}
+	for _, end := range []*time.Time{a.EndDate, b.EndDate} {
+		if end != nil && (o.To == nil || end.Before(*o.To)) {
+			v := *end
+			o.To = &v
+		}
+	}
+	return o
+}
+
+type SubscriptionOverlapDTO struct {
+	UserID          string   `json:"user_id"`
+	ServiceName     string   `json:"service_name"`
+	SubscriptionIDs [2]int64 `json:"subscription_ids"`
+	From            string   `json:"from"`         // MM-YYYY or YYYY-MM-DD, see FormatStartDate
+	To              *string  `json:"to,omitempty"` // MM-YYYY or YYYY-MM-DD, see FormatEndDate
+}
+
+func ToSubscriptionOverlapDTO(o SubscriptionOverlap) SubscriptionOverlapDTO {
+	var to *string
+	if o.To != nil {
+		v := FormatEndDate(*o.To)
+		to = &v
+	}
+	return SubscriptionOverlapDTO{
+		UserID:          o.UserID,
+		ServiceName:     o.ServiceName,
+		SubscriptionIDs: [2]int64{o.FirstID, o.SecondID},
+		From:            FormatStartDate(o.From),
+		To:              to,
+	}
+}
+
+// DuplicateFilter filters the list of overlapping subscriptions.
+type DuplicateFilter struct {
+	UserID      *string
+	ServiceName *string
+}
@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// Pause freezes billing of a subscription from From through Until, both month starts, inclusive.
+type Pause struct {
+	From  time.Time  `db:"paused_from" json:"-"`
+	Until *time.Time `db:"paused_until" json:"-"` // last paused month, NULL = until resumed
+}
+
+type PauseDTO struct {
+	From  string  `json:"from"`            // MM-YYYY
+	Until *string `json:"until,omitempty"` // MM-YYYY, absent while paused indefinitely
+}
+
+func ToPauseDTO(p Pause) PauseDTO {
+	dto := PauseDTO{From: FormatMonthYear(p.From)}
+	if p.Until != nil {
+		v := FormatMonthYear(*p.Until)
+		dto.Until = &v
+	}
+	return dto
+}
+
+// Covers reports whether month m is inside the pause.
+func (p Pause) Covers(m time.Time) bool {
+	m = MonthStartUTC(m)
+	return !p.From.After(m) && (p.Until == nil || !p.Until.Before(m))
+}
+
+// Overlaps reports whether two pauses share at least one month.
+func (p Pause) Overlaps(o Pause) bool {
+	return (p.Until == nil || !p.Until.Before(o.From)) && (o.Until == nil || !o.Until.Before(p.From))
+}
+
+// PausedIn reports whether billing is paused in month m.
+func (s Subscription) PausedIn(m time.Time) bool {
+	return slices.ContainsFunc(s.Pauses, func(p Pause) bool { return p.Covers(m) })
+}
+
+// PauseAt returns the index of the pause covering month m, or -1.
+func (s Subscription) PauseAt(m time.Time) int {
+	return slices.IndexFunc(s.Pauses, func(p Pause) bool { return p.Covers(m) })
+}
+
+// BilledBetween reports whether the subscription has a month in [from, to] that is within
+// its range and not paused; nil bounds are open. Must stay in sync with the overlap clause
+// in postgres.buildWhereList.
+func (s Subscription) BilledBetween(from, to *time.Time) bool {
+	lo := MonthStartUTC(s.StartDate)
+	if from != nil && from.After(lo) {
+		lo = MonthStartUTC(*from)
+	}
+	hi := s.EndDate
+	if to != nil && (hi == nil || to.Before(*hi)) {
+		hi = to
+	}
+
+	// первый неприостановленный месяц — либо lo, либо месяц сразу после какой-то паузы
+	candidates := []time.Time{lo}
+	for _, p := range s.Pauses {
+		if p.Until != nil {
+			candidates = append(candidates, NextMonthStartUTC(*p.Until))
+		}
+	}
+	for _, m := range candidates {
+		if m.Before(lo) || (hi != nil && m.After(MonthStartUTC(*hi))) {
+			continue
+		}
+		if !s.PausedIn(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// StateAt returns the state of the subscription in month m:
+// upcoming, ended, paused, trial or active, checked in this order.
+func (s Subscription) StateAt(m time.Time) ListStatus {
+	m = MonthStartUTC(m)
+	switch {
+	case MonthStartUTC(s.StartDate).After(m):
+		return StatusUpcoming
+	case s.EndDate != nil && s.EndDate.Before(m):
+		return StatusEnded
+	case s.PausedIn(m):
+		return StatusPaused
+	case s.InTrial(m):
+		return StatusTrial
+	default:
+		return StatusActive
+	}
+}
+
+// ChargedIn reports whether anything is charged for the subscription in month m.
+func (s Subscription) ChargedIn(m time.Time) bool {
+	return s.StateAt(m) == StatusActive && s.BillingPeriod.ChargesInMonth(s.StartDate, s.EndDate, m) > 0
+}
+
+// ResumedAt cuts the pause short so that month at is billed again.
+// ok is false when nothing is left of the pause, i.e. at is its first month.
+func (p Pause) ResumedAt(at time.Time) (_ Pause, ok bool) {
+	last := MonthStartUTC(at).AddDate(0, -1, 0)
+	if last.Before(p.From) {
+		return p, false
+	}
+	p.Until = &last
+	return p, true
+}
+
+// SubscriptionDetailDTO is a subscription together with its state in a given month.
+type SubscriptionDetailDTO struct {
+	SubscriptionDTO
+	State ListStatus `json:"state"`
+}
+
+func ToDetailDTO(s Subscription, m time.Time) SubscriptionDetailDTO {
+	return SubscriptionDetailDTO{SubscriptionDTO: ToDTO(s), State: s.StateAt(m)}
+}
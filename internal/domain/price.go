@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// PriceChange sets the subscription price starting from EffectiveFrom (month start).
+// A price is valid until the next change.
+type PriceChange struct {
+	SubscriptionID int64     `db:"subscription_id"`
+	EffectiveFrom  time.Time `db:"effective_from"`
+	Price          int64     `db:"price"`
+}
+
+type PriceChangeDTO struct {
+	EffectiveFrom string `json:"effective_from"` // MM-YYYY
+	Price         int64  `json:"price"`
+}
+
+func ToPriceChangeDTO(p PriceChange) PriceChangeDTO {
+	return PriceChangeDTO{
+		EffectiveFrom: FormatMonthYear(p.EffectiveFrom),
+		Price:         p.Price,
+	}
+}
+
+// PriceAt returns the price valid in month m, or fallback if no change is effective yet.
+// history must be sorted by EffectiveFrom.
+func PriceAt(history []PriceChange, m time.Time, fallback int64) int64 {
+	price := fallback
+	for _, p := range history {
+		if p.EffectiveFrom.After(m) {
+			break
+		}
+		price = p.Price
+	}
+	return price
+}
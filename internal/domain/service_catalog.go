@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Service is an entry of the services catalog. Subscriptions reference it by ServiceID
+// and keep its canonical Name in service_name.
+type Service struct {
+	ID           int64     `db:"id"`
+	Name         string    `db:"name"` // canonical name
+	Aliases      []string  `db:"-"`
+	Category     *string   `db:"category"`
+	DefaultPrice *int64    `db:"default_price"` // used when a subscription is created without price
+	CreatedAt    time.Time `db:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at"`
+}
+
+type ServiceDTO struct {
+	ID           int64    `json:"id"`
+	Name         string   `json:"name"`
+	Aliases      []string `json:"aliases"`
+	Category     *string  `json:"category,omitempty"`
+	DefaultPrice *int64   `json:"default_price,omitempty"`
+}
+
+func ToServiceDTO(s Service) ServiceDTO {
+	aliases := s.Aliases
+	if aliases == nil {
+		aliases = []string{}
+	}
+	return ServiceDTO{
+		ID:           s.ID,
+		Name:         s.Name,
+		Aliases:      aliases,
+		Category:     s.Category,
+		DefaultPrice: s.DefaultPrice,
+	}
+}
+
+// ServiceFilter filters the catalog list.
+type ServiceFilter struct {
+	Category *string
+}
+
+// CleanServiceName trims a name and collapses inner whitespace: "  Yandex   Plus " -> "Yandex Plus".
+func CleanServiceName(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// ServiceNameKey is the lookup key of a name or alias: names differing only in case
+// and whitespace resolve to the same service.
+// Must stay in sync with the backfill in migrations/0009_services.up.sql.
+func ServiceNameKey(s string) string {
+	return strings.ToLower(CleanServiceName(s))
+}
@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SortField is a whitelisted column List can be ordered by.
+type SortField string
+
+const (
+	SortByID          SortField = "id"
+	SortByPrice       SortField = "price"
+	SortByStartDate   SortField = "start_date"
+	SortByServiceName SortField = "service_name"
+)
+
+// ListSort orders List by Field, ties are broken by id in the same direction.
+// The zero value orders by id ascending.
+type ListSort struct {
+	Field SortField
+	Desc  bool
+}
+
+// ParseListSort parses "field" or "-field" (descending).
+func ParseListSort(s string) (ListSort, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ListSort{Field: SortByID}, nil
+	}
+
+	var out ListSort
+	if strings.HasPrefix(s, "-") {
+		out.Desc = true
+		s = s[1:]
+	}
+	switch f := SortField(s); f {
+	case SortByID, SortByPrice, SortByStartDate, SortByServiceName:
+		out.Field = f
+		return out, nil
+	default:
+		return ListSort{}, fmt.Errorf("unsupported sort %q (allowed: id, price, start_date, service_name, optionally prefixed with '-')", s)
+	}
+}
+
+func (s ListSort) field() SortField {
+	if s.Field == "" {
+		return SortByID
+	}
+	return s.Field
+}
+
+func (s ListSort) String() string {
+	if s.Desc {
+		return "-" + string(s.field())
+	}
+	return string(s.field())
+}
+
+// Key returns the sort-key value of sub as stored in a cursor.
+func (s ListSort) Key(sub Subscription) string {
+	switch s.field() {
+	case SortByPrice:
+		return strconv.FormatInt(sub.Price, 10)
+	case SortByStartDate:
+		return sub.StartDate.UTC().Format(time.DateOnly)
+	case SortByServiceName:
+		return sub.ServiceName
+	default:
+		return ""
+	}
+}
+
+// ParseKey converts a cursor key back to the column type: int64, time.Time or string.
+// Sorting by id has no separate key and returns nil.
+func (s ListSort) ParseKey(key string) (any, error) {
+	switch s.field() {
+	case SortByPrice:
+		v, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return v, nil
+	case SortByStartDate:
+		v, err := time.Parse(time.DateOnly, key)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return v, nil
+	case SortByServiceName:
+		return key, nil
+	default:
+		return nil, nil
+	}
+}
+
+// Compare orders a and b as List does: -1 if a goes first, 1 if b does, 0 for the same row.
+func (s ListSort) Compare(a, b Subscription) int {
+	c := 0
+	switch s.field() {
+	case SortByPrice:
+		c = cmp.Compare(a.Price, b.Price)
+	case SortByStartDate:
+		c = a.StartDate.Compare(b.StartDate)
+	case SortByServiceName:
+		c = strings.Compare(a.ServiceName, b.ServiceName)
+	}
+	if c == 0 {
+		c = cmp.Compare(a.ID, b.ID)
+	}
+	if s.Desc {
+		return -c
+	}
+	return c
+}
+
+// ListStatus filters subscriptions by their state in a given month.
+type ListStatus string
+
+const (
+	StatusActive   ListStatus = "active"   // идёт в этом месяце
+	StatusEnded    ListStatus = "ended"    // закончилась раньше этого месяца
+	StatusUpcoming ListStatus = "upcoming" // начнётся позже этого месяца
+	StatusTrial    ListStatus = "trial"    // идёт бесплатный пробный период (частный случай active)
+	StatusPaused   ListStatus = "paused"   // идёт, но оплата приостановлена; в active не входит
+)
+
+func ParseListStatus(s string) (ListStatus, error) {
+	switch st := ListStatus(strings.ToLower(strings.TrimSpace(s))); st {
+	case StatusActive, StatusEnded, StatusUpcoming, StatusTrial, StatusPaused:
+		return st, nil
+	default:
+		return "", fmt.Errorf("unsupported status %q (allowed: active, ended, upcoming, trial, paused)", s)
+	}
+}
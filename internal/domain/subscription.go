@@ -28,39 +28,163 @@ func FormatMonthYear(t time.Time) string {
 	return MonthStartUTC(t).Format(MonthYearLayout)
 }
 
+// MonthEndUTC returns the last day of the month of t.
+func MonthEndUTC(t time.Time) time.Time {
+	return NextMonthStartUTC(t).AddDate(0, 0, -1)
+}
+
+// ParseStartDate parses a start date given as "MM-YYYY" (the first day of the month)
+// or as "YYYY-MM-DD".
+func ParseStartDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := ParseMonthYear(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date format %q (expected MM-YYYY or YYYY-MM-DD)", s)
+	}
+	return t, nil
+}
+
+// ParseEndDate parses an end date given as "MM-YYYY" (the month is included, so the last day of it)
+// or as "YYYY-MM-DD", the last day the subscription is in effect.
+func ParseEndDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := ParseMonthYear(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date format %q (expected MM-YYYY or YYYY-MM-DD)", s)
+	}
+	return MonthEndUTC(t), nil
+}
+
+// FormatStartDate is the inverse of ParseStartDate: "MM-YYYY" for the first day of a month, else "YYYY-MM-DD".
+func FormatStartDate(t time.Time) string {
+	if t.Day() == 1 {
+		return FormatMonthYear(t)
+	}
+	return t.Format(time.DateOnly)
+}
+
+// FormatEndDate is the inverse of ParseEndDate: "MM-YYYY" for the last day of a month, else "YYYY-MM-DD".
+func FormatEndDate(t time.Time) string {
+	if t.Equal(MonthEndUTC(t)) {
+		return FormatMonthYear(t)
+	}
+	return t.Format(time.DateOnly)
+}
+
 type Subscription struct {
-	ID          int64      `db:"id" json:"id"`
-	ServiceName string     `db:"service_name" json:"service_name"`
-	Price       int64      `db:"price" json:"price"`     // rubles per month
-	UserID      string     `db:"user_id" json:"user_id"` // UUID as string
-	StartDate   time.Time  `db:"start_date" json:"-"`
-	EndDate     *time.Time `db:"end_date" json:"-"` // month start or NULL
-	CreatedAt   time.Time  `db:"created_at" json:"-"`
-	UpdatedAt   time.Time  `db:"updated_at" json:"-"`
+	ID            int64                `db:"id" json:"id"`
+	ServiceID     *int64               `db:"service_id" json:"service_id"`     // catalog entry, NULL for rows not yet resolved
+	ServiceName   string               `db:"service_name" json:"service_name"` // canonical name of ServiceID
+	Price         int64                `db:"price" json:"price"`               // in Currency per billing period
+	Currency      string               `db:"currency" json:"currency"`
+	BillingPeriod BillingPeriod        `db:"billing_period" json:"billing_period"`
+	UserID        string               `db:"user_id" json:"user_id"` // UUID as string
+	StartDate     time.Time            `db:"start_date" json:"-"`    // first day in effect
+	EndDate       *time.Time           `db:"end_date" json:"-"`      // last day in effect or NULL
+	TrialEnd      *time.Time           `db:"trial_end" json:"-"`     // last free month (start) or NULL
+	CreatedAt     time.Time            `db:"created_at" json:"-"`
+	UpdatedAt     time.Time            `db:"updated_at" json:"-"`
+	DeletedAt     *time.Time           `db:"deleted_at" json:"-"`    // soft delete, NULL = active
+	Version       int64                `db:"version" json:"version"` // incremented on every change, used as ETag
+	Tags          []string             `db:"-" json:"tags"`          // normalized and sorted, see NormalizeTags
+	Members       []SubscriptionMember `db:"-" json:"members"`       // share the cost by weight, see Shares; empty = UserID pays alone
+	Pauses        []Pause              `db:"-" json:"-"`             // sorted by From, see PausedIn
 }
 
 type SubscriptionDTO struct {
-	ID          int64   `json:"id"`
-	ServiceName string  `json:"service_name"`
-	Price       int64   `json:"price"`
-	UserID      string  `json:"user_id"`
-	StartDate   string  `json:"start_date"` // MM-YYYY
-	EndDate     *string `json:"end_date,omitempty"`
+	ID            int64                `json:"id"`
+	ServiceID     *int64               `json:"service_id,omitempty"`
+	ServiceName   string               `json:"service_name"`
+	Price         int64                `json:"price"`
+	Currency      string               `json:"currency"`
+	BillingPeriod string               `json:"billing_period"`
+	UserID        string               `json:"user_id"`
+	StartDate     string               `json:"start_date"`           // MM-YYYY or YYYY-MM-DD, see FormatStartDate
+	EndDate       *string              `json:"end_date,omitempty"`   // MM-YYYY or YYYY-MM-DD, see FormatEndDate
+	TrialEnd      *string              `json:"trial_end,omitempty"`  // MM-YYYY
+	DeletedAt     *string              `json:"deleted_at,omitempty"` // RFC 3339
+	Version       int64                `json:"version"`
+	Tags          []string             `json:"tags"`
+	Members       []SubscriptionMember `json:"members,omitempty"`
+	Pauses        []PauseDTO           `json:"pauses,omitempty"`
 }
 
 func ToDTO(s Subscription) SubscriptionDTO {
 	var end *string
 	if s.EndDate != nil {
-		v := FormatMonthYear(*s.EndDate)
+		v := FormatEndDate(*s.EndDate)
 		end = &v
 	}
 
+	var trialEnd *string
+	if s.TrialEnd != nil {
+		v := FormatMonthYear(*s.TrialEnd)
+		trialEnd = &v
+	}
+
+	var deleted *string
+	if s.DeletedAt != nil {
+		v := s.DeletedAt.UTC().Format(time.RFC3339)
+		deleted = &v
+	}
+
+	tags := s.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	var pauses []PauseDTO
+	for _, p := range s.Pauses {
+		pauses = append(pauses, ToPauseDTO(p))
+	}
+
 	return SubscriptionDTO{
-		ID:          s.ID,
-		ServiceName: s.ServiceName,
-		Price:       s.Price,
-		UserID:      s.UserID,
-		StartDate:   FormatMonthYear(s.StartDate),
-		EndDate:     end,
+		ID:            s.ID,
+		ServiceID:     s.ServiceID,
+		ServiceName:   s.ServiceName,
+		Price:         s.Price,
+		Currency:      s.Currency,
+		BillingPeriod: string(s.BillingPeriod),
+		UserID:        s.UserID,
+		StartDate:     FormatStartDate(s.StartDate),
+		EndDate:       end,
+		TrialEnd:      trialEnd,
+		DeletedAt:     deleted,
+		Version:       s.Version,
+		Tags:          tags,
+		Members:       s.Members,
+		Pauses:        pauses,
 	}
 }
+
+// ActiveIn reports whether the subscription is in effect on at least one day of month m.
+func (s Subscription) ActiveIn(m time.Time) bool {
+	m = MonthStartUTC(m)
+	return !MonthStartUTC(s.StartDate).After(m) && (s.EndDate == nil || !s.EndDate.Before(m))
+}
+
+// CoveredFraction returns the share of days of month m the subscription is in effect, from 0 to 1.
+func (s Subscription) CoveredFraction(m time.Time) float64 {
+	first, last := MonthStartUTC(m), MonthEndUTC(m)
+	if s.StartDate.After(first) {
+		first = s.StartDate
+	}
+	if s.EndDate != nil && s.EndDate.Before(last) {
+		last = *s.EndDate
+	}
+	days := last.Sub(first).Hours()/24 + 1
+	if days <= 0 {
+		return 0
+	}
+	return days / float64(MonthEndUTC(m).Day())
+}
+
+// InTrial reports whether month m is a free trial month: from StartDate through TrialEnd inclusive.
+// Nothing is charged for trial months.
+func (s Subscription) InTrial(m time.Time) bool {
+	return s.TrialEnd != nil && !MonthStartUTC(m).After(MonthStartUTC(*s.TrialEnd))
+}
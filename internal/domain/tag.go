@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	MaxTagLength        = 50
+	MaxSubscriptionTags = 20
+)
+
+// NormalizeTag lowercases a tag and collapses whitespace: " Dev  Tools" -> "dev tools".
+func NormalizeTag(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// NormalizeTags normalizes tags, drops empty ones and duplicates and sorts the result,
+// so a tag set has a single representation.
+func NormalizeTags(tags []string) ([]string, error) {
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = NormalizeTag(t)
+		if t == "" {
+			continue
+		}
+		if len([]rune(t)) > MaxTagLength {
+			return nil, fmt.Errorf("tag %q is too long (max %d characters)", t, MaxTagLength)
+		}
+		out = append(out, t)
+	}
+	slices.Sort(out)
+	out = slices.Compact(out)
+	if len(out) > MaxSubscriptionTags {
+		return nil, fmt.Errorf("too many tags (max %d)", MaxSubscriptionTags)
+	}
+	return out, nil
+}
@@ -0,0 +1,59 @@
+package domain
+
+import "time"
+
+// CostTotal is the cost of a period before and after discounts.
+type CostTotal struct {
+	Gross    int64 `db:"gross"`
+	Discount int64 `db:"discount"`
+	Net      int64 `db:"net"` // Gross - Discount
+}
+
+// MonthlyCost is one month of a total cost breakdown.
+type MonthlyCost struct {
+	Month         time.Time `db:"month"`         // month start (UTC)
+	Amount        int64     `db:"amount"`        // sum charged in this month, after discounts
+	Subscriptions int64     `db:"subscriptions"` // subscriptions contributing to Amount
+}
+
+type MonthlyCostDTO struct {
+	Month         string `json:"month"` // MM-YYYY
+	Amount        int64  `json:"amount"`
+	Subscriptions int64  `json:"subscriptions"`
+}
+
+func ToMonthlyCostDTO(c MonthlyCost) MonthlyCostDTO {
+	return MonthlyCostDTO{
+		Month:         FormatMonthYear(c.Month),
+		Amount:        c.Amount,
+		Subscriptions: c.Subscriptions,
+	}
+}
+
+// GroupTotal is the total cost of one group; only the grouped fields are set.
+type GroupTotal struct {
+	ServiceName *string `db:"service_name"`
+	UserID      *string `db:"user_id"`
+	Tag         *string `db:"tag"` // nil for untagged subscriptions when grouped by tag
+	CostTotal
+}
+
+type GroupTotalDTO struct {
+	ServiceName *string `json:"service_name,omitempty"`
+	UserID      *string `json:"user_id,omitempty"`
+	Tag         *string `json:"tag,omitempty"`
+	Gross       int64   `json:"gross"`
+	Discount    int64   `json:"discount"`
+	Net         int64   `json:"net"`
+}
+
+func ToGroupTotalDTO(g GroupTotal) GroupTotalDTO {
+	return GroupTotalDTO{
+		ServiceName: g.ServiceName,
+		UserID:      g.UserID,
+		Tag:         g.Tag,
+		Gross:       g.Gross,
+		Discount:    g.Discount,
+		Net:         g.Net,
+	}
+}
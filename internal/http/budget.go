@@ -0,0 +1,215 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"subscription_service/internal/domain"
+	"subscription_service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateBudgetRequest struct {
+	Amount   int64   `json:"amount" binding:"required" example:"5000"` // limit per month
+	Currency string  `json:"currency" example:"RUB"`                   // ISO 4217, default RUB
+	Tag      *string `json:"tag" example:"entertainment"`              // count only subscriptions with this tag
+}
+
+type UpdateBudgetRequest struct {
+	Amount   *int64  `json:"amount"`
+	Currency *string `json:"currency"`
+	Tag      *string `json:"tag"` // "" counts all subscriptions
+}
+
+// CreateBudget godoc
+// @Summary Create budget
+// @Description Set a monthly spending limit for a user. The spend of a month is the net cost
+// @Description of the user's share of subscriptions, as in /subscriptions/total; with 'tag' only tagged subscriptions count.
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID (UUID)"
+// @Param request body CreateBudgetRequest true "Budget payload"
+// @Success 201 {object} domain.BudgetDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/users/{user_id}/budgets [post]
+func (h *Handler) CreateBudget(c *gin.Context) {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req CreateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	b, err := h.budgets.Create(c.Request.Context(), userID, service.CreateBudgetRequest{
+		Amount:   req.Amount,
+		Currency: req.Currency,
+		Tag:      req.Tag,
+	})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, domain.ToBudgetDTO(*b))
+}
+
+// ListBudgets godoc
+// @Summary List budgets
+// @Tags budgets
+// @Produce json
+// @Param user_id path string true "User ID (UUID)"
+// @Success 200 {array} domain.BudgetDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/users/{user_id}/budgets [get]
+func (h *Handler) ListBudgets(c *gin.Context) {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	items, err := h.budgets.List(c.Request.Context(), userID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	out := make([]domain.BudgetDTO, 0, len(items))
+	for _, b := range items {
+		out = append(out, domain.ToBudgetDTO(b))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// UpdateBudget godoc
+// @Summary Update budget
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID (UUID)"
+// @Param budget_id path int true "Budget ID"
+// @Param request body UpdateBudgetRequest true "Partial update payload"
+// @Success 200 {object} domain.BudgetDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/users/{user_id}/budgets/{budget_id} [patch]
+func (h *Handler) UpdateBudget(c *gin.Context) {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "budget_id")
+	if !ok {
+		return
+	}
+
+	var req UpdateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	b, err := h.budgets.Update(c.Request.Context(), userID, id, service.UpdateBudgetRequest{
+		Amount:   req.Amount,
+		Currency: req.Currency,
+		Tag:      req.Tag,
+	})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.ToBudgetDTO(*b))
+}
+
+// DeleteBudget godoc
+// @Summary Delete budget
+// @Description Delete a budget together with its recorded alerts
+// @Tags budgets
+// @Param user_id path string true "User ID (UUID)"
+// @Param budget_id path int true "Budget ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/users/{user_id}/budgets/{budget_id} [delete]
+func (h *Handler) DeleteBudget(c *gin.Context) {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "budget_id")
+	if !ok {
+		return
+	}
+
+	if err := h.budgets.Delete(c.Request.Context(), userID, id); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListBudgetAlerts godoc
+// @Summary List budget alerts
+// @Description Months in which the user's spend went over a budget, newest first.
+// @Description Alerts are recorded by a periodic check of the current month, one per budget and month.
+// @Tags budgets
+// @Produce json
+// @Param user_id path string true "User ID (UUID)"
+// @Param limit query int false "Limit, default 50, max 200"
+// @Param offset query int false "Offset"
+// @Success 200 {array} domain.BudgetAlertDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/users/{user_id}/budgets/alerts [get]
+func (h *Handler) ListBudgetAlerts(c *gin.Context) {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	f := domain.BudgetAlertFilter{
+		UserID: userID,
+		Limit:  50,
+		Offset: 0,
+	}
+	if v := strings.TrimSpace(c.Query("limit")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'limit'"})
+			return
+		}
+		f.Limit = n
+	}
+	if v := strings.TrimSpace(c.Query("offset")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'offset'"})
+			return
+		}
+		f.Offset = n
+	}
+
+	items, err := h.budgets.Alerts(c.Request.Context(), f)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	out := make([]domain.BudgetAlertDTO, 0, len(items))
+	for _, a := range items {
+		out = append(out, domain.ToBudgetAlertDTO(a))
+	}
+	c.JSON(http.StatusOK, out)
+}
@@ -0,0 +1,229 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"subscription_service/internal/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Calendar godoc
+// @Summary User calendar feed
+// @Description iCalendar (RFC 5545) feed of a user's subscriptions that have not ended yet:
+// @Description a recurring event on each charge date and a one-off event on the last day of the subscription.
+// @Description Charges start after the free trial; the last day of the trial gets its own event.
+// @Description Charge events carry no amount: it varies with price changes, discounts and shares.
+// @Description Charges in paused months are excluded; an open-ended pause ends the series.
+// @Tags subscriptions
+// @Produce text/calendar
+// @Param user_id path string true "User ID (UUID)"
+// @Success 200 {string} string "iCalendar feed"
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/users/{user_id}/calendar.ics [get]
+func (h *Handler) Calendar(c *gin.Context) {
+	userID := strings.TrimSpace(c.Param("user_id"))
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id (expected UUID)"})
+		return
+	}
+
+	now := time.Now().UTC()
+	from := domain.MonthStartUTC(now)
+	f := domain.ListFilter{UserIDs: []string{userID}, From: &from}
+
+	var subs []domain.Subscription
+	err := h.svc.Export(c.Request.Context(), f, func(s domain.Subscription) error {
+		subs = append(subs, s)
+		return nil
+	})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="calendar.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(buildCalendar(subs, now)))
+}
+
+// buildCalendar собирает VCALENDAR: по событию на списания и на окончание каждой подписки.
+func buildCalendar(subs []domain.Subscription, now time.Time) string {
+	var b icalBuilder
+	b.line("BEGIN:VCALENDAR")
+	b.line("VERSION:2.0")
+	b.line("PRODID:-//subscription_service//calendar//EN")
+	b.line("CALSCALE:GREGORIAN")
+	b.line("METHOD:PUBLISH")
+	b.line("X-WR-CALNAME:Subscriptions")
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, s := range subs {
+		lastDay := s.EndDate
+
+		// пробный период (или бессрочная пауза) целиком до конца подписки — списаний нет
+		first, lastCharge := firstPaidCharge(s), lastChargeDay(s)
+		if lastCharge == nil || !first.After(*lastCharge) {
+			b.line("BEGIN:VEVENT")
+			b.line(fmt.Sprintf("UID:subscription-%d-charge@subscription_service", s.ID))
+			b.line("DTSTAMP:" + stamp)
+			b.line("DTSTART;VALUE=DATE:" + icalDate(first))
+			rrule := "RRULE:" + icalRecurrence(s.BillingPeriod, s.StartDate)
+			if lastCharge != nil {
+				rrule += ";UNTIL=" + icalDate(*lastCharge)
+			}
+			b.line(rrule)
+			if paused := pausedCharges(s, first, lastCharge); len(paused) > 0 {
+				b.line("EXDATE;VALUE=DATE:" + strings.Join(paused, ","))
+			}
+			// сумма меняется от списания к списанию (история цен, скидки, доли участников),
+			// а у повторяющегося события SUMMARY одна — поэтому без суммы
+			b.line("SUMMARY:" + icalText(fmt.Sprintf("%s: %s charge", s.ServiceName, s.BillingPeriod)))
+			b.line("TRANSP:TRANSPARENT")
+			b.line("END:VEVENT")
+		}
+
+		if s.TrialEnd != nil {
+			b.line("BEGIN:VEVENT")
+			b.line(fmt.Sprintf("UID:subscription-%d-trial-end@subscription_service", s.ID))
+			b.line("DTSTAMP:" + stamp)
+			b.line("DTSTART;VALUE=DATE:" + icalDate(domain.NextMonthStartUTC(*s.TrialEnd).AddDate(0, 0, -1)))
+			b.line("SUMMARY:" + icalText(s.ServiceName+": free trial ends"))
+			b.line("TRANSP:TRANSPARENT")
+			b.line("END:VEVENT")
+		}
+
+		if lastDay != nil {
+			b.line("BEGIN:VEVENT")
+			b.line(fmt.Sprintf("UID:subscription-%d-end@subscription_service", s.ID))
+			b.line("DTSTAMP:" + stamp)
+			b.line("DTSTART;VALUE=DATE:" + icalDate(*lastDay))
+			b.line("SUMMARY:" + icalText(s.ServiceName+": subscription ends"))
+			b.line("TRANSP:TRANSPARENT")
+			b.line("END:VEVENT")
+		}
+	}
+
+	b.line("END:VCALENDAR")
+	return b.String()
+}
+
+// firstPaidCharge — первая дата списания после пробного периода, на той же сетке, что и RRULE от start_date.
+func firstPaidCharge(s domain.Subscription) time.Time {
+	if s.TrialEnd == nil {
+		return s.StartDate
+	}
+	paidFrom := domain.NextMonthStartUTC(*s.TrialEnd)
+	d := s.StartDate
+	for i := 1; d.Before(paidFrom); i++ {
+		d = s.BillingPeriod.ChargeDate(s.StartDate, i)
+	}
+	return d
+}
+
+// lastChargeDay — последний день, в который возможны списания: конец подписки
+// или день перед бессрочной паузой; nil — без ограничения.
+func lastChargeDay(s domain.Subscription) *time.Time {
+	last := s.EndDate
+	for _, p := range s.Pauses {
+		if p.Until != nil {
+			continue
+		}
+		if d := p.From.AddDate(0, 0, -1); last == nil || d.Before(*last) {
+			last = &d
+		}
+	}
+	return last
+}
+
+// pausedCharges — даты списаний с first по last, попавшие в приостановленные месяцы (для EXDATE).
+// Бессрочную паузу уже отрезал lastChargeDay, поэтому перебор идёт до конца последней ограниченной паузы.
+func pausedCharges(s domain.Subscription, first time.Time, last *time.Time) []string {
+	var limit time.Time // первый день после последней паузы
+	for _, p := range s.Pauses {
+		if p.Until != nil && domain.NextMonthStartUTC(*p.Until).After(limit) {
+			limit = domain.NextMonthStartUTC(*p.Until)
+		}
+	}
+	if last != nil && last.Before(limit) {
+		limit = last.AddDate(0, 0, 1)
+	}
+
+	var out []string
+	for i := 0; ; i++ {
+		d := s.BillingPeriod.ChargeDate(s.StartDate, i)
+		if !d.Before(limit) {
+			break
+		}
+		if !d.Before(first) && s.PausedIn(d) {
+			out = append(out, icalDate(d))
+		}
+	}
+	return out
+}
+
+// icalRecurrence переводит период оплаты в правило повторения от даты начала start.
+// Голое FREQ=MONTHLY с 31-го числа пропускает короткие месяцы (RFC 5545, 3.3.10), поэтому
+// с 29-го числа и позже берётся последний существующий из дней 28..start.Day(),
+// как в domain.BillingPeriod.ChargeDate.
+func icalRecurrence(p domain.BillingPeriod, start time.Time) string {
+	switch p {
+	case domain.BillingWeekly:
+		return "FREQ=WEEKLY"
+	case domain.BillingYearly:
+		if start.Month() == time.February && start.Day() == 29 {
+			return "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=28,29;BYSETPOS=-1"
+		}
+		return "FREQ=YEARLY"
+	}
+
+	rule := "FREQ=MONTHLY"
+	if p == domain.BillingQuarterly {
+		rule += ";INTERVAL=3"
+	}
+	if day := start.Day(); day > 28 {
+		days := make([]string, 0, 4)
+		for d := 28; d <= day; d++ {
+			days = append(days, strconv.Itoa(d))
+		}
+		rule += ";BYMONTHDAY=" + strings.Join(days, ",") + ";BYSETPOS=-1"
+	}
+	return rule
+}
+
+func icalDate(t time.Time) string {
+	return t.UTC().Format("20060102")
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func icalText(s string) string {
+	return icalEscaper.Replace(s)
+}
+
+// icalBuilder пишет строки с CRLF и переносит их длиннее 75 октетов (RFC 5545, 3.1).
+type icalBuilder struct {
+	strings.Builder
+}
+
+func (b *icalBuilder) line(s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		// не разрезаем многобайтный символ UTF-8
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74 // пробел в начале строки продолжения тоже считается
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,240 @@
+package http
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"subscription_service/internal/domain"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestFirstPaidCharge(t *testing.T) {
+	tests := []struct {
+		name     string
+		period   domain.BillingPeriod
+		start    time.Time
+		trialEnd *time.Time
+		want     time.Time
+	}{
+		{name: "no trial", period: domain.BillingMonthly, start: date(2025, 1, 15), want: date(2025, 1, 15)},
+		{name: "monthly after trial", period: domain.BillingMonthly, start: date(2025, 1, 15), trialEnd: ptr(date(2025, 2, 1)), want: date(2025, 3, 15)},
+		{name: "31st clamped in february", period: domain.BillingMonthly, start: date(2025, 1, 31), trialEnd: ptr(date(2025, 1, 1)), want: date(2025, 2, 28)},
+		{name: "31st clamped in april", period: domain.BillingMonthly, start: date(2025, 1, 31), trialEnd: ptr(date(2025, 3, 1)), want: date(2025, 4, 30)},
+		{name: "quarterly clamped", period: domain.BillingQuarterly, start: date(2024, 11, 30), trialEnd: ptr(date(2025, 1, 1)), want: date(2025, 2, 28)},
+		{name: "yearly from leap day", period: domain.BillingYearly, start: date(2024, 2, 29), trialEnd: ptr(date(2024, 12, 1)), want: date(2025, 2, 28)},
+		{name: "weekly", period: domain.BillingWeekly, start: date(2025, 1, 30), trialEnd: ptr(date(2025, 1, 1)), want: date(2025, 2, 6)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := domain.Subscription{BillingPeriod: tt.period, StartDate: tt.start, TrialEnd: tt.trialEnd}
+			if got := firstPaidCharge(s); !got.Equal(tt.want) {
+				t.Fatalf("expected %s, got %s", icalDate(tt.want), icalDate(got))
+			}
+		})
+	}
+}
+
+func TestIcalRecurrence(t *testing.T) {
+	tests := []struct {
+		period domain.BillingPeriod
+		start  time.Time
+		want   string
+	}{
+		{domain.BillingMonthly, date(2025, 1, 15), "FREQ=MONTHLY"},
+		{domain.BillingMonthly, date(2025, 1, 28), "FREQ=MONTHLY"},
+		{domain.BillingMonthly, date(2025, 1, 30), "FREQ=MONTHLY;BYMONTHDAY=28,29,30;BYSETPOS=-1"},
+		{domain.BillingMonthly, date(2025, 1, 31), "FREQ=MONTHLY;BYMONTHDAY=28,29,30,31;BYSETPOS=-1"},
+		{domain.BillingQuarterly, date(2025, 1, 29), "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=28,29;BYSETPOS=-1"},
+		{domain.BillingYearly, date(2025, 1, 31), "FREQ=YEARLY"},
+		{domain.BillingYearly, date(2024, 2, 29), "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=28,29;BYSETPOS=-1"},
+		{domain.BillingWeekly, date(2025, 1, 31), "FREQ=WEEKLY"},
+	}
+	for _, tt := range tests {
+		if got := icalRecurrence(tt.period, tt.start); got != tt.want {
+			t.Errorf("%s from %s: expected %q, got %q", tt.period, icalDate(tt.start), tt.want, got)
+		}
+	}
+}
+
+func TestIcalText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Netflix", "Netflix"},
+		{"a,b;c", `a\,b\;c`},
+		{`back\slash`, `back\\slash`},
+		{"two\nlines\r\nthree", `two\nlines\nthree`},
+	}
+	for _, tt := range tests {
+		if got := icalText(tt.in); got != tt.want {
+			t.Errorf("icalText(%q): expected %q, got %q", tt.in, tt.want, got)
+		}
+	}
+}
+
+func TestIcalBuilder_FoldsLongLines(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{name: "short", in: "SUMMARY:Netflix"},
+		{name: "exactly 75", in: "SUMMARY:" + strings.Repeat("a", 67)},
+		{name: "ascii", in: "SUMMARY:" + strings.Repeat("a", 200)},
+		{name: "multibyte", in: "SUMMARY:" + strings.Repeat("подписка ", 30)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b icalBuilder
+			b.line(tt.in)
+			out := b.String()
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("expected CRLF at the end, got %q", out)
+			}
+
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			for i, l := range lines {
+				if len(l) > 75 {
+					t.Fatalf("line %d is %d octets", i, len(l))
+				}
+				if !utf8.ValidString(l) {
+					t.Fatalf("line %d splits a UTF-8 character: %q", i, l)
+				}
+				if i > 0 && !strings.HasPrefix(l, " ") {
+					t.Fatalf("continuation line %d does not start with a space: %q", i, l)
+				}
+			}
+			if len(tt.in) <= 75 && len(lines) != 1 {
+				t.Fatalf("expected no folding, got %d lines", len(lines))
+			}
+
+			// развёртка по RFC 5545: убрать CRLF с последующим пробелом
+			if got := strings.ReplaceAll(strings.TrimSuffix(out, "\r\n"), "\r\n ", ""); got != tt.in {
+				t.Fatalf("unfolded line differs:\n%q\n%q", got, tt.in)
+			}
+		})
+	}
+}
+
+func TestBuildCalendar(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		sub     domain.Subscription
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "open-ended monthly",
+			sub:  domain.Subscription{ID: 1, ServiceName: "Netflix", Price: 400, Currency: "RUB", BillingPeriod: domain.BillingMonthly, StartDate: date(2025, 1, 15)},
+			want: []string{
+				"UID:subscription-1-charge@subscription_service",
+				"DTSTART;VALUE=DATE:20250115",
+				"RRULE:FREQ=MONTHLY",
+				"SUMMARY:Netflix: monthly charge",
+			},
+			notWant: []string{"UNTIL=", "subscription-1-end", "400"},
+		},
+		{
+			name: "ends on a date",
+			sub:  domain.Subscription{ID: 2, ServiceName: "Spotify", BillingPeriod: domain.BillingMonthly, StartDate: date(2025, 1, 31), EndDate: ptr(date(2025, 6, 30))},
+			want: []string{
+				"RRULE:FREQ=MONTHLY;BYMONTHDAY=28,29,30,31;BYSETPOS=-1;UNTIL=20250630",
+				"UID:subscription-2-end@subscription_service",
+				"DTSTART;VALUE=DATE:20250630",
+				"SUMMARY:Spotify: subscription ends",
+			},
+		},
+		{
+			name: "trial",
+			sub:  domain.Subscription{ID: 3, ServiceName: "Okko", BillingPeriod: domain.BillingMonthly, StartDate: date(2025, 1, 31), TrialEnd: ptr(date(2025, 1, 1))},
+			want: []string{
+				"DTSTART;VALUE=DATE:20250228",
+				"UID:subscription-3-trial-end@subscription_service",
+				"DTSTART;VALUE=DATE:20250131",
+				"SUMMARY:Okko: free trial ends",
+			},
+		},
+		{
+			name:    "trial until the end",
+			sub:     domain.Subscription{ID: 4, ServiceName: "Kion", BillingPeriod: domain.BillingMonthly, StartDate: date(2025, 1, 1), TrialEnd: ptr(date(2025, 3, 1)), EndDate: ptr(date(2025, 3, 31))},
+			want:    []string{"subscription-4-trial-end", "subscription-4-end"},
+			notWant: []string{"subscription-4-charge", "RRULE"},
+		},
+		{
+			name: "paused months",
+			sub: domain.Subscription{ID: 6, ServiceName: "Netflix", BillingPeriod: domain.BillingMonthly, StartDate: date(2025, 1, 15), Pauses: []domain.Pause{
+				{From: date(2025, 3, 1), Until: ptr(date(2025, 4, 1))},
+			}},
+			want:    []string{"RRULE:FREQ=MONTHLY", "EXDATE;VALUE=DATE:20250315,20250415"},
+			notWant: []string{"UNTIL="},
+		},
+		{
+			name: "open-ended pause ends the series",
+			sub: domain.Subscription{ID: 7, ServiceName: "Netflix", BillingPeriod: domain.BillingWeekly, StartDate: date(2025, 1, 6), Pauses: []domain.Pause{
+				{From: date(2025, 2, 1), Until: ptr(date(2025, 2, 1))},
+				{From: date(2025, 4, 1)},
+			}},
+			want: []string{
+				"RRULE:FREQ=WEEKLY;UNTIL=20250331",
+				"EXDATE;VALUE=DATE:20250203,20250210,20250217,20250224",
+			},
+			notWant: []string{"subscription-7-end"},
+		},
+		{
+			name:    "paused before the first charge",
+			sub:     domain.Subscription{ID: 8, ServiceName: "Netflix", BillingPeriod: domain.BillingMonthly, StartDate: date(2025, 1, 15), TrialEnd: ptr(date(2025, 2, 1)), Pauses: []domain.Pause{{From: date(2025, 3, 1)}}},
+			want:    []string{"subscription-8-trial-end"},
+			notWant: []string{"subscription-8-charge"},
+		},
+		{
+			name: "escaped name",
+			sub:  domain.Subscription{ID: 5, ServiceName: "Music, Video; TV", BillingPeriod: domain.BillingYearly, StartDate: date(2025, 2, 1)},
+			want: []string{`SUMMARY:Music\, Video\; TV: yearly charge`, "RRULE:FREQ=YEARLY"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := buildCalendar([]domain.Subscription{tt.sub}, now)
+			if !strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n") || !strings.HasSuffix(out, "END:VCALENDAR\r\n") {
+				t.Fatalf("unexpected calendar envelope:\n%s", out)
+			}
+			if !strings.Contains(out, "DTSTAMP:20250310T123000Z\r\n") {
+				t.Fatalf("expected DTSTAMP from now:\n%s", out)
+			}
+			if strings.Count(out, "BEGIN:VEVENT") != strings.Count(out, "END:VEVENT") {
+				t.Fatalf("unbalanced VEVENT:\n%s", out)
+			}
+
+			lines := strings.Split(out, "\r\n")
+			for _, w := range tt.want {
+				if !contains(lines, w) {
+					t.Errorf("expected line %q in:\n%s", w, out)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(out, w) {
+					t.Errorf("unexpected %q in:\n%s", w, out)
+				}
+			}
+		})
+	}
+}
+
+// contains ищет строку целиком или как подстроку, если want не похоже на целое свойство.
+func contains(lines []string, want string) bool {
+	for _, l := range lines {
+		if l == want || (!strings.Contains(want, ":") && strings.Contains(l, want)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"net/http"
+
+	"subscription_service/internal/domain"
+	"subscription_service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AddDiscountRequest struct {
+	Kind   string  `json:"kind" binding:"required" example:"percent"` // percent | fixed
+	Value  int64   `json:"value" binding:"required" example:"50"`     // 1..100 for percent, amount per month in the subscription currency for fixed
+	Code   *string `json:"code" example:"SPRING50"`                   // promo code, informational
+	From   string  `json:"from" binding:"required" example:"03-2025"` // MM-YYYY, first discounted month
+	Until  *string `json:"until" example:"05-2025"`                   // MM-YYYY, last discounted month
+	Months *int    `json:"months" example:"3"`                        // instead of until: number of discounted months
+}
+
+// AddDiscount godoc
+// @Summary Add subscription discount
+// @Description Add a percentage or fixed discount for a range of months.
+// @Description Without 'until' and 'months' the discount has no end. Discounts in effect in the same month add up,
+// @Description but never exceed the cost of that month.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param request body AddDiscountRequest true "Discount payload"
+// @Success 201 {object} domain.DiscountDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/subscriptions/{id}/discounts [post]
+func (h *Handler) AddDiscount(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req AddDiscountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	d, err := h.svc.AddDiscount(c.Request.Context(), id, service.AddDiscountRequest{
+		Kind:   req.Kind,
+		Value:  req.Value,
+		Code:   req.Code,
+		From:   req.From,
+		Until:  req.Until,
+		Months: req.Months,
+	})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, domain.ToDiscountDTO(d))
+}
+
+// ListDiscounts godoc
+// @Summary List subscription discounts
+// @Description List discounts of a subscription by first month
+// @Tags subscriptions
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Success 200 {array} domain.DiscountDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/subscriptions/{id}/discounts [get]
+func (h *Handler) ListDiscounts(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	items, err := h.svc.Discounts(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	out := make([]domain.DiscountDTO, 0, len(items))
+	for _, d := range items {
+		out = append(out, domain.ToDiscountDTO(d))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// DeleteDiscount godoc
+// @Summary Delete subscription discount
+// @Description Remove a discount; totals are recalculated without it
+// @Tags subscriptions
+// @Param id path int true "Subscription ID"
+// @Param discount_id path int true "Discount ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/subscriptions/{id}/discounts/{discount_id} [delete]
+func (h *Handler) DeleteDiscount(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	discountID, ok := parseIDParam(c, "discount_id")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DeleteDiscount(c.Request.Context(), id, discountID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"subscription_service/internal/domain"
+	"subscription_service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExchangeRateRequest struct {
+	Month    string  `json:"month" binding:"required"`    // MM-YYYY
+	Currency string  `json:"currency" binding:"required"` // ISO 4217
+	Rate     float64 `json:"rate" binding:"required"`     // RUB per 1 unit of currency
+}
+
+// UpsertExchangeRates godoc
+// @Summary Load exchange rates
+// @Description Create or replace monthly exchange rates to RUB
+// @Tags exchange-rates
+// @Accept json
+// @Param request body []ExchangeRateRequest true "Rates"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/exchange-rates [put]
+func (h *Handler) UpsertExchangeRates(c *gin.Context) {
+	var req []ExchangeRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	svcReq := make([]service.UpsertExchangeRateRequest, 0, len(req))
+	for _, r := range req {
+		svcReq = append(svcReq, service.UpsertExchangeRateRequest{
+			Month:    r.Month,
+			Currency: r.Currency,
+			Rate:     r.Rate,
+		})
+	}
+
+	if err := h.rates.Upsert(c.Request.Context(), svcReq); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListExchangeRates godoc
+// @Summary List exchange rates
+// @Description Get monthly exchange rates to RUB
+// @Tags exchange-rates
+// @Produce json
+// @Param currency query string false "Currency (ISO 4217)"
+// @Param from query string false "Start month (MM-YYYY)"
+// @Param to query string false "End month (MM-YYYY)"
+// @Success 200 {array} domain.ExchangeRateDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/exchange-rates [get]
+func (h *Handler) ListExchangeRates(c *gin.Context) {
+	var f domain.ExchangeRateFilter
+
+	if v := strings.TrimSpace(c.Query("currency")); v != "" {
+		currency, err := domain.ParseCurrency(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'currency' (expected ISO 4217 code)"})
+			return
+		}
+		f.Currency = &currency
+	}
+	if v := strings.TrimSpace(c.Query("from")); v != "" {
+		t, err := domain.ParseMonthYear(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'from' (expected MM-YYYY)"})
+			return
+		}
+		f.From = &t
+	}
+	if v := strings.TrimSpace(c.Query("to")); v != "" {
+		t, err := domain.ParseMonthYear(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'to' (expected MM-YYYY)"})
+			return
+		}
+		f.To = &t
+	}
+
+	items, err := h.rates.List(c.Request.Context(), f)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	out := make([]domain.ExchangeRateDTO, 0, len(items))
+	for _, r := range items {
+		out = append(out, domain.ToExchangeRateDTO(r))
+	}
+	c.JSON(http.StatusOK, out)
+}
@@ -0,0 +1,219 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"subscription_service/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	exportFormatCSV   = "csv"
+	exportFormatJSONL = "jsonl"
+)
+
+var subscriptionExportColumns = []string{
+	"id", "service_name", "price", "currency", "billing_period", "user_id", "start_date", "end_date", "deleted_at", "version", "tags", "trial_end",
+}
+
+// tagSeparator разделяет теги в одной ячейке CSV (экспорт и импорт).
+const tagSeparator = ";"
+
+var breakdownExportColumns = []string{"month", "amount", "subscriptions", "currency"}
+
+// Export godoc
+// @Summary Export subscriptions
+// @Description Stream all subscriptions matching the filters as CSV or JSON Lines, without the list page limit.
+// @Description Filters and sort are the same as in GET /api/v1/subscriptions.
+// @Tags subscriptions
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "csv (default) or jsonl"
+// @Param user_id query []string false "User IDs (UUID), repeated or comma-separated" collectionFormat(multi)
+// @Param service_name query string false "Service name (exact)"
+// @Param tag query []string false "Tags, any of; repeated or comma-separated" collectionFormat(multi)
+// @Param service_name_prefix query string false "Service name prefix, case-insensitive"
+// @Param from query string false "Start month (MM-YYYY)"
+// @Param to query string false "End month (MM-YYYY)"
+// @Param price_min query int false "Minimal price"
+// @Param price_max query int false "Maximal price"
+// @Param status query string false "active, ended, upcoming, trial or paused relative to status_at"
+// @Param status_at query string false "Month for status (MM-YYYY), default current month"
+// @Param sort query string false "id, price, start_date or service_name; prefix '-' for descending"
+// @Param include_deleted query bool false "Include soft-deleted subscriptions"
+// @Success 200 {string} string "CSV or JSON Lines"
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/subscriptions/export [get]
+func (h *Handler) Export(c *gin.Context) {
+	format, ok := parseExportFormat(c)
+	if !ok {
+		return
+	}
+	f, ok := parseListFilter(c)
+	if !ok {
+		return
+	}
+
+	var enc *exportEncoder
+	start := func() {
+		enc = startExport(c, format, "subscriptions", subscriptionExportColumns)
+	}
+
+	err := h.svc.Export(c.Request.Context(), f, func(s domain.Subscription) error {
+		if enc == nil {
+			start()
+		}
+		dto := domain.ToDTO(s)
+		return enc.write(dto, []string{
+			strconv.FormatInt(dto.ID, 10),
+			dto.ServiceName,
+			strconv.FormatInt(dto.Price, 10),
+			dto.Currency,
+			string(dto.BillingPeriod),
+			dto.UserID,
+			dto.StartDate,
+			derefString(dto.EndDate),
+			derefString(dto.DeletedAt),
+			strconv.FormatInt(dto.Version, 10),
+			strings.Join(dto.Tags, tagSeparator),
+			derefString(dto.TrialEnd),
+		})
+	})
+	if err != nil && enc == nil {
+		writeError(c, err)
+		return
+	}
+	if enc == nil {
+		start()
+	}
+	finishExport(c, enc, err)
+}
+
+// ExportBreakdown godoc
+// @Summary Export monthly cost breakdown
+// @Description Monthly cost breakdown as CSV or JSON Lines, parameters as in GET /api/v1/subscriptions/total/breakdown
+// @Tags subscriptions
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "csv (default) or jsonl"
+// @Param from query string true "Start month (MM-YYYY)"
+// @Param to query string true "End month (MM-YYYY)"
+// @Param user_id query string false "User ID (UUID); shared subscriptions count only this user's share"
+// @Param service_name query string false "Service name"
+// @Param tag query []string false "Tags, any of; repeated or comma-separated" collectionFormat(multi)
+// @Param currency query string false "Currency of the result (ISO 4217), default RUB"
+// @Param proration query string false "daily: charge monthly subscriptions for the days they cover in their first and last month"
+// @Success 200 {string} string "CSV or JSON Lines"
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/subscriptions/total/breakdown/export [get]
+func (h *Handler) ExportBreakdown(c *gin.Context) {
+	format, ok := parseExportFormat(c)
+	if !ok {
+		return
+	}
+	f, ok := parseTotalFilter(c)
+	if !ok {
+		return
+	}
+
+	// разбивка — по строке на месяц, её можно собрать целиком до начала ответа
+	items, err := h.svc.TotalBreakdown(c.Request.Context(), f)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	currency := f.TargetCurrency()
+	enc := startExport(c, format, "breakdown", breakdownExportColumns)
+	for _, m := range items {
+		dto := domain.ToMonthlyCostDTO(m)
+		row := struct {
+			domain.MonthlyCostDTO
+			Currency string `json:"currency"`
+		}{dto, currency}
+		err = enc.write(row, []string{
+			dto.Month,
+			strconv.FormatInt(dto.Amount, 10),
+			strconv.FormatInt(dto.Subscriptions, 10),
+			currency,
+		})
+		if err != nil {
+			break
+		}
+	}
+	finishExport(c, enc, err)
+}
+
+func parseExportFormat(c *gin.Context) (string, bool) {
+	switch format := strings.ToLower(strings.TrimSpace(c.Query("format"))); format {
+	case "", exportFormatCSV:
+		return exportFormatCSV, true
+	case exportFormatJSONL:
+		return exportFormatJSONL, true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'format' (expected csv or jsonl)"})
+		return "", false
+	}
+}
+
+// exportEncoder пишет строки выгрузки прямо в ответ.
+type exportEncoder struct {
+	csv  *csv.Writer
+	json *json.Encoder
+}
+
+// startExport отправляет заголовки ответа; после этого статус уже не поменять.
+func startExport(c *gin.Context, format, name string, columns []string) *exportEncoder {
+	c.Header("Content-Disposition", `attachment; filename="`+name+"."+format+`"`)
+
+	var w io.Writer = c.Writer
+	if format == exportFormatJSONL {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+		return &exportEncoder{json: json.NewEncoder(w)}
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	enc := &exportEncoder{csv: csv.NewWriter(w)}
+	_ = enc.csv.Write(columns)
+	return enc
+}
+
+func (e *exportEncoder) write(v any, record []string) error {
+	if e.json != nil {
+		return e.json.Encode(v)
+	}
+	return e.csv.Write(record)
+}
+
+// finishExport дописывает буфер. Ошибку посреди выгрузки клиенту уже не сообщить:
+// она попадает в лог, а ответ обрывается.
+func finishExport(c *gin.Context, e *exportEncoder, err error) {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err == nil {
+			err = e.csv.Error()
+		}
+	}
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
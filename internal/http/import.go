@@ -0,0 +1,231 @@
+package http
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"subscription_service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportBodySize ограничивает размер загружаемого CSV.
+const maxImportBodySize = 10 << 20
+
+// importColumns — порядок колонок CSV без заголовка.
+var importColumns = []string{"service_name", "price", "user_id", "start_date", "end_date"}
+
+// importOptionalColumns можно передать только с заголовком.
+var importOptionalColumns = []string{"currency", "billing_period", "tags", "trial_end"}
+
+type ImportRowResponse struct {
+	Line  int    `json:"line"`
+	ID    *int64 `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type ImportResponse struct {
+	Imported int                 `json:"imported"`
+	Failed   int                 `json:"failed"`
+	Rows     []ImportRowResponse `json:"rows"`
+}
+
+// Import godoc
+// @Summary Import subscriptions from CSV
+// @Description CSV columns: service_name, price, user_id, start_date, end_date (MM-YYYY or YYYY-MM-DD, end_date may be empty).
+// @Description An optional header row allows any column order and the extra columns currency, billing_period,
+// @Description tags (separated by ';') and trial_end (MM-YYYY, last free month).
+// @Description Valid rows are inserted in one transaction; with mode=strict nothing is inserted if any row is invalid.
+// @Tags subscriptions
+// @Accept text/csv
+// @Accept mpfd
+// @Produce json
+// @Param mode query string false "partial (default) or strict"
+// @Param file formData file false "CSV file (multipart), otherwise the request body is read"
+// @Success 200 {object} ImportResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ImportResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/subscriptions/import [post]
+func (h *Handler) Import(c *gin.Context) {
+	strict := false
+	switch mode := strings.TrimSpace(c.Query("mode")); mode {
+	case "", "partial":
+	case "strict":
+		strict = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'mode' (expected partial or strict)"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBodySize)
+
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing 'file'"})
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot read 'file'"})
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+
+	rows, err := parseImportCSV(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.svc.Import(c.Request.Context(), rows, strict)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	resp := ImportResponse{
+		Imported: result.Imported,
+		Failed:   result.Failed,
+		Rows:     make([]ImportRowResponse, 0, len(result.Rows)),
+	}
+	for _, r := range result.Rows {
+		resp.Rows = append(resp.Rows, ImportRowResponse{Line: r.Line, ID: r.ID, Error: r.Error})
+	}
+
+	status := http.StatusOK
+	if strict && result.Failed > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, resp)
+}
+
+// parseImportCSV читает строки импорта. Ошибки отдельных строк попадают в ImportRow.Err,
+// ошибка возвращается только если файл не удалось прочитать целиком.
+func parseImportCSV(r io.Reader) ([]service.ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	columns := map[string]int{}
+	for i, name := range importColumns {
+		columns[name] = i
+	}
+
+	var rows []service.ImportRow
+	first := true
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var maxBytes *http.MaxBytesError
+			if errors.As(err, &maxBytes) {
+				return nil, fmt.Errorf("file is too large (max %d bytes)", maxImportBodySize)
+			}
+			return nil, fmt.Errorf("invalid csv: %v", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		if first {
+			first = false
+			if isImportHeader(record) {
+				if columns, err = parseImportHeader(record); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+
+		if len(rows) >= service.MaxImportRows {
+			return nil, fmt.Errorf("too many rows (max %d)", service.MaxImportRows)
+		}
+		rows = append(rows, parseImportRecord(line, record, columns))
+	}
+	return rows, nil
+}
+
+func isImportHeader(record []string) bool {
+	for _, v := range record {
+		if strings.EqualFold(strings.TrimSpace(v), "service_name") {
+			return true
+		}
+	}
+	return false
+}
+
+func parseImportHeader(record []string) (map[string]int, error) {
+	columns := make(map[string]int, len(record))
+	for i, v := range record {
+		name := strings.ToLower(strings.TrimSpace(v))
+		known := false
+		for _, c := range importColumns {
+			known = known || c == name
+		}
+		for _, c := range importOptionalColumns {
+			known = known || c == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown csv column %q", v)
+		}
+		columns[name] = i
+	}
+	for _, name := range importColumns {
+		if _, ok := columns[name]; !ok && name != "end_date" {
+			return nil, fmt.Errorf("missing csv column %q", name)
+		}
+	}
+	return columns, nil
+}
+
+func parseImportRecord(line int, record []string, columns map[string]int) service.ImportRow {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row := service.ImportRow{
+		Line: line,
+		Request: service.CreateSubscriptionRequest{
+			ServiceName:   field("service_name"),
+			Currency:      field("currency"),
+			BillingPeriod: field("billing_period"),
+			UserID:        field("user_id"),
+			StartDate:     field("start_date"),
+		},
+	}
+
+	// пустая цена — берётся default_price из каталога сервисов
+	if v := field("price"); v != "" {
+		price, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || price <= 0 {
+			row.Err = fmt.Errorf("%w: invalid price", service.ErrInvalidInput)
+			return row
+		}
+		row.Request.Price = price
+	}
+
+	if end := field("end_date"); end != "" {
+		row.Request.EndDate = &end
+	}
+	if trialEnd := field("trial_end"); trialEnd != "" {
+		row.Request.TrialEnd = &trialEnd
+	}
+	if tags := field("tags"); tags != "" {
+		row.Request.Tags = strings.Split(tags, tagSeparator)
+	}
+	return row
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"strings"
+
+	"subscription_service/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActorHeader identifies who performs the request; it is written to the audit log.
+// Authentication is not implemented, so the value is trusted as is.
+const ActorHeader = "X-Actor"
+
+func Actor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if actor := strings.TrimSpace(c.GetHeader(ActorHeader)); actor != "" {
+			c.Request = c.Request.WithContext(domain.WithActor(c.Request.Context(), actor))
+		}
+		c.Next()
+	}
+}
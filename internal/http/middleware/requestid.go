@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"subscription_service/internal/domain"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -14,6 +16,7 @@ func RequestID() gin.HandlerFunc {
 			rid = uuid.NewString()
 		}
 		c.Set(RequestIDKey, rid)
+		c.Request = c.Request.WithContext(domain.WithRequestID(c.Request.Context(), rid))
 		c.Header("X-Request-ID", rid)
 		c.Next()
 	}
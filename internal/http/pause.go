@@ -0,0 +1,115 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"subscription_service/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PauseSubscriptionRequest struct {
+	From  *string `json:"from" example:"03-2025"`  // MM-YYYY, по умолчанию текущий месяц
+	Until *string `json:"until" example:"05-2025"` // MM-YYYY, последний приостановленный месяц; null — до resume
+}
+
+type ResumeSubscriptionRequest struct {
+	At *string `json:"at" example:"06-2025"` // MM-YYYY, первый оплачиваемый месяц; по умолчанию текущий
+}
+
+// Pause godoc
+// @Summary Pause subscription
+// @Description Stop billing from 'from' through 'until' inclusive; without 'until' the pause lasts until resumed.
+// @Description Paused months are excluded from totals. The body is optional, 'from' defaults to the current month.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param request body PauseSubscriptionRequest false "Pause period"
+// @Success 200 {object} domain.SubscriptionDetailDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/subscriptions/{id}/pause [post]
+func (h *Handler) Pause(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req PauseSubscriptionRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+	now := time.Now()
+	from := domain.FormatMonthYear(now)
+	if req.From != nil {
+		from = *req.From
+	}
+
+	sub, err := h.svc.Pause(c.Request.Context(), id, from, req.Until)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	setETag(c, sub.Version)
+	c.JSON(http.StatusOK, domain.ToDetailDTO(*sub, now))
+}
+
+// Resume godoc
+// @Summary Resume subscription
+// @Description Resume billing from month 'at' by ending the pause that covers it; 'at' defaults to the current month.
+// @Description Resuming in the first month of a pause cancels the pause.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param request body ResumeSubscriptionRequest false "Resume month"
+// @Success 200 {object} domain.SubscriptionDetailDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/subscriptions/{id}/resume [post]
+func (h *Handler) Resume(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req ResumeSubscriptionRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+	now := time.Now()
+	at := domain.FormatMonthYear(now)
+	if req.At != nil {
+		at = *req.At
+	}
+
+	sub, err := h.svc.Resume(c.Request.Context(), id, at)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	setETag(c, sub.Version)
+	c.JSON(http.StatusOK, domain.ToDetailDTO(*sub, now))
+}
+
+// bindOptionalJSON разбирает тело, если оно есть; пустое тело оставляет req без изменений.
+func bindOptionalJSON(c *gin.Context, req any) bool {
+	if c.Request.ContentLength == 0 {
+		return true
+	}
+	if err := c.ShouldBindJSON(req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return false
+	}
+	return true
+}
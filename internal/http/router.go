@@ -11,6 +11,7 @@ import (
 func NewRouter(h *Handler) *gin.Engine {
 	r := gin.New()
 	r.Use(middleware.RequestID())
+	r.Use(middleware.Actor())
 	r.Use(gin.Logger(), gin.Recovery())
 
 	r.GET("/health", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
@@ -18,12 +19,42 @@ func NewRouter(h *Handler) *gin.Engine {
 	v1 := r.Group("/api/v1")
 	{
 		v1.POST("/subscriptions", h.Create)
+		v1.POST("/subscriptions/import", h.Import)
 		v1.GET("/subscriptions/:id", h.GetByID)
 		v1.PATCH("/subscriptions/:id", h.Update)
 		v1.DELETE("/subscriptions/:id", h.Delete)
+		v1.POST("/subscriptions/:id/restore", h.Restore)
+		v1.POST("/subscriptions/:id/pause", h.Pause)
+		v1.POST("/subscriptions/:id/resume", h.Resume)
+		v1.GET("/subscriptions/:id/prices", h.PriceHistory)
+		v1.POST("/subscriptions/:id/discounts", h.AddDiscount)
+		v1.GET("/subscriptions/:id/discounts", h.ListDiscounts)
+		v1.DELETE("/subscriptions/:id/discounts/:discount_id", h.DeleteDiscount)
+		v1.GET("/subscriptions/:id/history", h.History)
 		v1.GET("/subscriptions", h.List)
+		v1.GET("/subscriptions/export", h.Export)
 
 		v1.GET("/subscriptions/total", h.Total)
+		v1.GET("/subscriptions/total/breakdown", h.TotalBreakdown)
+		v1.GET("/subscriptions/total/breakdown/export", h.ExportBreakdown)
+		v1.GET("/subscriptions/forecast", h.Forecast)
+		v1.GET("/subscriptions/duplicates", h.Duplicates)
+
+		v1.GET("/users/:user_id/calendar.ics", h.Calendar)
+		v1.POST("/users/:user_id/budgets", h.CreateBudget)
+		v1.GET("/users/:user_id/budgets", h.ListBudgets)
+		v1.GET("/users/:user_id/budgets/alerts", h.ListBudgetAlerts)
+		v1.PATCH("/users/:user_id/budgets/:budget_id", h.UpdateBudget)
+		v1.DELETE("/users/:user_id/budgets/:budget_id", h.DeleteBudget)
+
+		v1.POST("/services", h.CreateService)
+		v1.GET("/services", h.ListServices)
+		v1.GET("/services/:id", h.GetService)
+		v1.PATCH("/services/:id", h.UpdateService)
+		v1.DELETE("/services/:id", h.DeleteService)
+
+		v1.PUT("/exchange-rates", h.UpsertExchangeRates)
+		v1.GET("/exchange-rates", h.ListExchangeRates)
 	}
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
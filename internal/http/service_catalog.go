@@ -0,0 +1,176 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"subscription_service/internal/domain"
+	"subscription_service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateServiceRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	Aliases      []string `json:"aliases"`
+	Category     *string  `json:"category"`
+	DefaultPrice *int64   `json:"default_price"` // per billing period
+}
+
+type UpdateServiceRequest struct {
+	Name         *string   `json:"name"`
+	Aliases      *[]string `json:"aliases"`       // replaces the whole list
+	Category     *string   `json:"category"`      // "" clears
+	DefaultPrice *int64    `json:"default_price"` // 0 clears
+}
+
+// CreateService godoc
+// @Summary Create catalog service
+// @Description Add a service to the catalog. Subscriptions whose service_name matches the name
+// @Description or one of the aliases (case and whitespace insensitive) are linked to it.
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param request body CreateServiceRequest true "Service payload"
+// @Success 201 {object} domain.ServiceDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/services [post]
+func (h *Handler) CreateService(c *gin.Context) {
+	var req CreateServiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	svc, err := h.catalog.Create(c.Request.Context(), service.CreateServiceRequest{
+		Name:         req.Name,
+		Aliases:      req.Aliases,
+		Category:     req.Category,
+		DefaultPrice: req.DefaultPrice,
+	})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, domain.ToServiceDTO(*svc))
+}
+
+// GetService godoc
+// @Summary Get catalog service
+// @Tags services
+// @Produce json
+// @Param id path int true "Service ID"
+// @Success 200 {object} domain.ServiceDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/services/{id} [get]
+func (h *Handler) GetService(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	svc, err := h.catalog.GetByID(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.ToServiceDTO(*svc))
+}
+
+// UpdateService godoc
+// @Summary Update catalog service
+// @Description Partially update a catalog service. Renaming also updates service_name of its subscriptions.
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param request body UpdateServiceRequest true "Partial update payload"
+// @Success 200 {object} domain.ServiceDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/services/{id} [patch]
+func (h *Handler) UpdateService(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req UpdateServiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	svc, err := h.catalog.Update(c.Request.Context(), id, service.UpdateServiceRequest{
+		Name:         req.Name,
+		Aliases:      req.Aliases,
+		Category:     req.Category,
+		DefaultPrice: req.DefaultPrice,
+	})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.ToServiceDTO(*svc))
+}
+
+// DeleteService godoc
+// @Summary Delete catalog service
+// @Description Delete a catalog service that no subscription references
+// @Tags services
+// @Param id path int true "Service ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/services/{id} [delete]
+func (h *Handler) DeleteService(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.catalog.Delete(c.Request.Context(), id); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListServices godoc
+// @Summary List catalog services
+// @Tags services
+// @Produce json
+// @Param category query string false "Category"
+// @Success 200 {array} domain.ServiceDTO
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/services [get]
+func (h *Handler) ListServices(c *gin.Context) {
+	var f domain.ServiceFilter
+	if v := strings.TrimSpace(c.Query("category")); v != "" {
+		f.Category = &v
+	}
+
+	items, err := h.catalog.List(c.Request.Context(), f)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	out := make([]domain.ServiceDTO, 0, len(items))
+	for _, s := range items {
+		out = append(out, domain.ToServiceDTO(s))
+	}
+	c.JSON(http.StatusOK, out)
+}
@@ -1,15 +1,19 @@
 package http
 
 import (
+	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"subscription_service/internal/domain"
 	"subscription_service/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // ErrorResponse represents error message
@@ -18,69 +22,176 @@ type ErrorResponse struct {
 }
 
 type Handler struct {
-	svc *service.SubscriptionService
+	svc     *service.SubscriptionService
+	rates   *service.ExchangeRateService
+	idem    *service.IdempotencyService
+	catalog *service.CatalogService
+	budgets *service.BudgetService
 }
 
-func NewHandler(svc *service.SubscriptionService) *Handler {
-	return &Handler{svc: svc}
+func NewHandler(
+	svc *service.SubscriptionService,
+	rates *service.ExchangeRateService,
+	idem *service.IdempotencyService,
+	catalog *service.CatalogService,
+	budgets *service.BudgetService,
+) *Handler {
+	return &Handler{svc: svc, rates: rates, idem: idem, catalog: catalog, budgets: budgets}
 }
 
+// IdempotencyKeyHeader — повтор POST с тем же ключом возвращает сохранённый ответ.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
 type CreateSubscriptionRequest struct {
-	ServiceName string  `json:"service_name" binding:"required"`
-	Price       int64   `json:"price" binding:"required"`
-	UserID      string  `json:"user_id" binding:"required"`
-	StartDate   string  `json:"start_date" binding:"required"` // MM-YYYY
-	EndDate     *string `json:"end_date"`                      // MM-YYYY | null
+	ServiceName   string                      `json:"service_name" binding:"required"`
+	Price         *int64                      `json:"price"`          // per billing period; default_price from the services catalog if omitted
+	Currency      string                      `json:"currency"`       // ISO 4217, default RUB
+	BillingPeriod string                      `json:"billing_period"` // weekly | monthly | quarterly | yearly, default monthly
+	UserID        string                      `json:"user_id" binding:"required"`
+	StartDate     string                      `json:"start_date" binding:"required"` // MM-YYYY | YYYY-MM-DD
+	EndDate       *string                     `json:"end_date"`                      // MM-YYYY (whole month) | YYYY-MM-DD (last day) | null
+	TrialEnd      *string                     `json:"trial_end"`                     // MM-YYYY, last free month | null
+	Tags          []string                    `json:"tags"`
+	Members       []domain.SubscriptionMember `json:"members"` // cost split by weight; user_id gets a share only if listed
 }
 
 // Create godoc
 // @Summary Create subscription
-// @Description Create a new subscription for a user
+// @Description Create a new subscription for a user.
+// @Description A repeated request with the same Idempotency-Key replays the original response.
+// @Description 'warnings' lists the coming months in which this change takes a user of the subscription over a budget
+// @Description or increases the overrun; 'warnings_unavailable' is set if budgets could not be checked.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Unique key of the request"
+// @Param mode query string false "lenient (default) or strict: reject a subscription overlapping another one of the same user and service"
 // @Param request body CreateSubscriptionRequest true "Subscription payload"
-// @Success 201 {object} domain.SubscriptionDTO
+// @Success 201 {object} domain.SubscriptionChangeDTO
 // @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/v1/subscriptions [post]
 func (h *Handler) Create(c *gin.Context) {
+	strict, ok := parseOverlapMode(c)
+	if !ok {
+		return
+	}
+
 	var req CreateSubscriptionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 		return
 	}
 
-	svcReq := service.CreateSubscriptionRequest{
-		ServiceName: req.ServiceName,
-		Price:       req.Price,
-		UserID:      req.UserID,
-		StartDate:   req.StartDate,
-		EndDate:     req.EndDate,
+	key := strings.TrimSpace(c.GetHeader(IdempotencyKeyHeader))
+	if key == "" {
+		h.create(c, req, strict)
+		return
 	}
 
-	id, err := h.svc.Create(c.Request.Context(), svcReq)
+	// хэшируем разобранный запрос, а не сырое тело: пробелы и порядок полей не важны.
+	// Режим тоже меняет результат, поэтому входит в отпечаток.
+	normalized, _ := json.Marshal(req)
+	if strict {
+		normalized = append(normalized, " mode=strict"...)
+	}
+	hash := service.HashRequest(normalized)
+	rec, err := h.idem.Begin(c.Request.Context(), key, hash)
 	if err != nil {
 		writeError(c, err)
 		return
 	}
+	if rec != nil {
+		for name, value := range rec.Headers {
+			c.Header(name, value)
+		}
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(*rec.StatusCode, "application/json; charset=utf-8", rec.Response)
+		return
+	}
 
+	ctx := c.Request.Context()
+	status, body, ok := h.create(c, req, strict)
+	if !ok {
+		if err := h.idem.Release(ctx, key, hash); err != nil {
+			log.Printf("request %s: release of idempotency key %q failed: %v", domain.RequestIDFromContext(ctx), key, err)
+		}
+		return
+	}
+	// ответ уже отправлен; без сохранённой записи повтор с этим ключом получит 409, пока не истечёт аренда
+	if err := h.idem.Complete(ctx, key, hash, status, replayedHeaders(c), body); err != nil {
+		log.Printf("request %s: saving response for idempotency key %q failed: %v", domain.RequestIDFromContext(ctx), key, err)
+	}
+}
+
+// replayedHeaders — заголовки ответа, которые сохраняются для повторов по Idempotency-Key.
+func replayedHeaders(c *gin.Context) map[string]string {
+	headers := make(map[string]string)
+	for _, name := range []string{"ETag"} {
+		if v := c.Writer.Header().Get(name); v != "" {
+			headers[name] = v
+		}
+	}
+	return headers
+}
+
+// create создаёт подписку и пишет ответ; возвращает статус и тело ответа для сохранения.
+// strict — отклонить подписку, дублирующую существующую.
+func (h *Handler) create(c *gin.Context, req CreateSubscriptionRequest, strict bool) (int, []byte, bool) {
+	var price int64
+	if req.Price != nil {
+		price = *req.Price
+		if price <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "price must be > 0"})
+			return 0, nil, false
+		}
+	}
+
+	svcReq := service.CreateSubscriptionRequest{
+		ServiceName:    req.ServiceName,
+		Price:          price,
+		Currency:       req.Currency,
+		BillingPeriod:  req.BillingPeriod,
+		UserID:         req.UserID,
+		StartDate:      req.StartDate,
+		EndDate:        req.EndDate,
+		TrialEnd:       req.TrialEnd,
+		Tags:           req.Tags,
+		Members:        req.Members,
+		RejectOverlaps: strict,
+	}
+
+	id, check, err := h.svc.CreateWithBudgetCheck(c.Request.Context(), svcReq)
+	if err != nil {
+		writeError(c, err)
+		return 0, nil, false
+	}
+
+	var out any = gin.H{"id": id}
 	created, _ := h.svc.GetByID(c.Request.Context(), id)
 	if created != nil {
-		c.JSON(http.StatusCreated, domain.ToDTO(*created))
-		return
+		setETag(c, created.Version)
+		out = changeResponse(c, *created, check)
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"id": id})
+	body, err := json.Marshal(out)
+	if err != nil {
+		writeError(c, err)
+		return 0, nil, false
+	}
+	c.Data(http.StatusCreated, "application/json; charset=utf-8", body)
+	return http.StatusCreated, body, true
 }
 
 // GetByID godoc
 // @Summary Get subscription by ID
-// @Description Get subscription details by its ID
+// @Description Get subscription details by its ID, with its state in the current month and pause history
 // @Tags subscriptions
 // @Produce json
 // @Param id path int true "Subscription ID"
-// @Success 200 {object} domain.SubscriptionDTO
+// @Success 200 {object} domain.SubscriptionDetailDTO
 // @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
@@ -101,20 +212,31 @@ func (h *Handler) GetByID(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, domain.ToDTO(*sub))
+	setETag(c, sub.Version)
+	c.JSON(http.StatusOK, domain.ToDetailDTO(*sub, time.Now()))
 }
 
 // Update godoc
 // @Summary Update subscription
-// @Description Partially update subscription fields
+// @Description Partially update subscription fields.
+// @Description A new price applies to the whole subscription unless price_effective_from (MM-YYYY) is given.
+// @Description With If-Match the update is applied only if the subscription version still matches the ETag.
+// @Description Tags are replaced with 'tags' or changed with 'add_tags' / 'remove_tags'.
+// @Description 'members' replaces the list of users sharing the cost; null or [] makes the subscription personal.
+// @Description 'warnings' lists the coming months in which this change takes a user of the subscription over a budget
+// @Description or increases the overrun; 'warnings_unavailable' is set if budgets could not be checked.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param id path int true "Subscription ID"
+// @Param If-Match header string false "ETag of the subscription"
+// @Param mode query string false "lenient (default) or strict: reject the update if the subscription then overlaps another one of the same user and service"
 // @Param request body object true "Partial update payload"
-// @Success 200 {object} domain.SubscriptionDTO
+// @Success 200 {object} domain.SubscriptionChangeDTO
 // @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/v1/subscriptions/{id} [patch]
 func (h *Handler) Update(c *gin.Context) {
@@ -123,13 +245,22 @@ func (h *Handler) Update(c *gin.Context) {
 		return
 	}
 
+	ifMatch, ok := parseIfMatch(c)
+	if !ok {
+		return
+	}
+	strict, ok := parseOverlapMode(c)
+	if !ok {
+		return
+	}
+
 	var raw map[string]any
 	if err := c.ShouldBindJSON(&raw); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 		return
 	}
 
-	var req service.UpdateSubscriptionRequest
+	req := service.UpdateSubscriptionRequest{IfMatch: ifMatch, RejectOverlaps: strict}
 
 	if v, ok := raw["service_name"]; ok {
 		if s, ok := v.(string); ok {
@@ -153,6 +284,30 @@ func (h *Handler) Update(c *gin.Context) {
 		}
 		req.Price = &p
 	}
+	if v, ok := raw["price_effective_from"]; ok {
+		if s, ok := v.(string); ok {
+			req.PriceEffectiveFrom = &s
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "price_effective_from must be string"})
+			return
+		}
+	}
+	if v, ok := raw["currency"]; ok {
+		if s, ok := v.(string); ok {
+			req.Currency = &s
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "currency must be string"})
+			return
+		}
+	}
+	if v, ok := raw["billing_period"]; ok {
+		if s, ok := v.(string); ok {
+			req.BillingPeriod = &s
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "billing_period must be string"})
+			return
+		}
+	}
 	if v, ok := raw["user_id"]; ok {
 		if s, ok := v.(string); ok {
 			req.UserID = &s
@@ -170,6 +325,40 @@ func (h *Handler) Update(c *gin.Context) {
 		}
 	}
 
+	if v, ok := raw["tags"]; ok {
+		tags, ok := stringList(v)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tags must be array of strings"})
+			return
+		}
+		req.Tags = &tags
+	}
+	if v, ok := raw["add_tags"]; ok {
+		if req.AddTags, ok = stringList(v); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "add_tags must be array of strings"})
+			return
+		}
+	}
+	if v, ok := raw["remove_tags"]; ok {
+		if req.RemoveTags, ok = stringList(v); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "remove_tags must be array of strings"})
+			return
+		}
+	}
+
+	if v, ok := raw["members"]; ok {
+		members := []domain.SubscriptionMember{}
+		if v != nil {
+			// перекодируем кусок map[string]any в типизированный список
+			b, _ := json.Marshal(v)
+			if err := json.Unmarshal(b, &members); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "members must be array of {user_id, weight}"})
+				return
+			}
+		}
+		req.Members = &members
+	}
+
 	// end_date: 3-state
 	if _, exists := raw["end_date"]; exists {
 		if raw["end_date"] == nil {
@@ -186,23 +375,135 @@ func (h *Handler) Update(c *gin.Context) {
 		req.EndDate = service.EndDateNotProvided()
 	}
 
-	updated, err := h.svc.Update(c.Request.Context(), id, req)
+	// trial_end: 3-state, как end_date
+	if v, exists := raw["trial_end"]; exists {
+		if v == nil {
+			req.TrialEnd = service.EndDateSetNull()
+		} else {
+			s, ok := v.(string)
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "trial_end must be string or null"})
+				return
+			}
+			req.TrialEnd = service.EndDateSetValue(s)
+		}
+	}
+
+	updated, check, err := h.svc.UpdateWithBudgetCheck(c.Request.Context(), id, req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	setETag(c, updated.Version)
+	c.JSON(http.StatusOK, changeResponse(c, *updated, check))
+}
+
+// changeResponse собирает ответ создания и изменения подписки. Неудачная сверка с бюджетами
+// не отменяет изменения: ошибка пишется в лог, а в ответе warnings_unavailable вместо пустых warnings.
+func changeResponse(c *gin.Context, sub domain.Subscription, check service.BudgetCheck) domain.SubscriptionChangeDTO {
+	out := domain.ToSubscriptionChangeDTO(sub, check.Warnings)
+	if check.Err != nil {
+		log.Printf("request %s: budget check of subscription %d failed: %v",
+			domain.RequestIDFromContext(c.Request.Context()), sub.ID, check.Err)
+		out.WarningsUnavailable = true
+	}
+	return out
+}
+
+// PriceHistory godoc
+// @Summary Subscription price history
+// @Description List price changes of a subscription by effective month
+// @Tags subscriptions
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Success 200 {array} domain.PriceChangeDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/subscriptions/{id}/prices [get]
+func (h *Handler) PriceHistory(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	items, err := h.svc.PriceHistory(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	out := make([]domain.PriceChangeDTO, 0, len(items))
+	for _, p := range items {
+		out = append(out, domain.ToPriceChangeDTO(p))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// History godoc
+// @Summary Subscription change history
+// @Description Audit log of create, update and delete operations, newest first
+// @Tags subscriptions
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Success 200 {array} domain.SubscriptionEventDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/subscriptions/{id}/history [get]
+func (h *Handler) History(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	f := domain.EventFilter{
+		SubscriptionID: id,
+		Limit:          50,
+		Offset:         0,
+	}
+	if v := strings.TrimSpace(c.Query("limit")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'limit'"})
+			return
+		}
+		f.Limit = n
+	}
+	if v := strings.TrimSpace(c.Query("offset")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'offset'"})
+			return
+		}
+		f.Offset = n
+	}
+
+	items, err := h.svc.History(c.Request.Context(), f)
 	if err != nil {
 		writeError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, domain.ToDTO(*updated))
+	out := make([]domain.SubscriptionEventDTO, 0, len(items))
+	for _, e := range items {
+		out = append(out, domain.ToEventDTO(e))
+	}
+	c.JSON(http.StatusOK, out)
 }
 
 // Delete godoc
 // @Summary Delete subscription
-// @Description Delete subscription by ID
+// @Description Soft-delete subscription by ID; it can be restored until purged
 // @Tags subscriptions
 // @Param id path int true "Subscription ID"
+// @Param If-Match header string false "ETag of the subscription"
 // @Success 204 "No Content"
 // @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/v1/subscriptions/{id} [delete]
 func (h *Handler) Delete(c *gin.Context) {
@@ -211,7 +512,12 @@ func (h *Handler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.svc.Delete(c.Request.Context(), id); err != nil {
+	ifMatch, ok := parseIfMatch(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Delete(c.Request.Context(), id, ifMatch); err != nil {
 		writeError(c, err)
 		return
 	}
@@ -219,49 +525,64 @@ func (h *Handler) Delete(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// Restore godoc
+// @Summary Restore subscription
+// @Description Restore a soft-deleted subscription
+// @Tags subscriptions
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} domain.SubscriptionDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/subscriptions/{id}/restore [post]
+func (h *Handler) Restore(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	restored, err := h.svc.Restore(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	setETag(c, restored.Version)
+	c.JSON(http.StatusOK, domain.ToDTO(*restored))
+}
+
 // List godoc
 // @Summary List subscriptions
-// @Description Get list of subscriptions with optional filters
+// @Description Get list of subscriptions with optional filters.
+// @Description Without 'cursor' returns a bare array paged by limit/offset.
+// @Description With 'cursor' (empty for the first page) returns {items, next_cursor, total_count}.
 // @Tags subscriptions
 // @Produce json
-// @Param user_id query string false "User ID (UUID)"
-// @Param service_name query string false "Service name"
+// @Param user_id query []string false "User IDs (UUID), repeated or comma-separated" collectionFormat(multi)
+// @Param service_name query string false "Service name (exact)"
+// @Param tag query []string false "Tags, any of; repeated or comma-separated" collectionFormat(multi)
+// @Param service_name_prefix query string false "Service name prefix, case-insensitive"
 // @Param from query string false "Start month (MM-YYYY)"
 // @Param to query string false "End month (MM-YYYY)"
+// @Param price_min query int false "Minimal price"
+// @Param price_max query int false "Maximal price"
+// @Param status query string false "active, ended, upcoming, trial or paused relative to status_at"
+// @Param status_at query string false "Month for status (MM-YYYY), default current month"
+// @Param sort query string false "id, price, start_date or service_name; prefix '-' for descending"
+// @Param include_deleted query bool false "Include soft-deleted subscriptions"
 // @Param limit query int false "Limit"
 // @Param offset query int false "Offset"
-// @Success 200 {array} domain.SubscriptionDTO
+// @Param cursor query string false "Opaque cursor from next_cursor"
+// @Param include_total query bool false "Add total_count in cursor mode"
+// @Success 200 {object} domain.SubscriptionPageDTO
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/v1/subscriptions [get]
 func (h *Handler) List(c *gin.Context) {
-	f := domain.ListFilter{
-		Limit:  50,
-		Offset: 0,
-	}
-
-	if v := strings.TrimSpace(c.Query("user_id")); v != "" {
-		f.UserID = &v
-	}
-	if v := strings.TrimSpace(c.Query("service_name")); v != "" {
-		f.ServiceName = &v
-	}
-
-	if v := strings.TrimSpace(c.Query("from")); v != "" {
-		t, err := domain.ParseMonthYear(v)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'from' (expected MM-YYYY)"})
-			return
-		}
-		f.From = &t
-	}
-	if v := strings.TrimSpace(c.Query("to")); v != "" {
-		t, err := domain.ParseMonthYear(v)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'to' (expected MM-YYYY)"})
-			return
-		}
-		f.To = &t
+	f, ok := parseListFilter(c)
+	if !ok {
+		return
 	}
 
 	if v := strings.TrimSpace(c.Query("limit")); v != "" {
@@ -281,6 +602,11 @@ func (h *Handler) List(c *gin.Context) {
 		f.Offset = n
 	}
 
+	if _, cursorMode := c.GetQuery("cursor"); cursorMode {
+		h.listPage(c, f)
+		return
+	}
+
 	items, err := h.svc.List(c.Request.Context(), f)
 	if err != nil {
 		writeError(c, err)
@@ -294,66 +620,258 @@ func (h *Handler) List(c *gin.Context) {
 	c.JSON(http.StatusOK, out)
 }
 
+func (h *Handler) listPage(c *gin.Context, f domain.ListFilter) {
+	if v := strings.TrimSpace(c.Query("cursor")); v != "" {
+		cursor, err := domain.DecodeCursor(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'cursor'"})
+			return
+		}
+		f.After = &cursor
+	}
+
+	withTotal := false
+	if v := strings.TrimSpace(c.Query("include_total")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'include_total'"})
+			return
+		}
+		withTotal = b
+	}
+
+	page, err := h.svc.ListPage(c.Request.Context(), f, withTotal)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.ToSubscriptionPageDTO(*page))
+}
+
 // Total godoc
 // @Summary Calculate total subscription cost
-// @Description Calculate total cost of subscriptions for a given period
+// @Description Calculate total cost of subscriptions for a given period: gross, discount and net (gross - discount).
+// @Description With group_by the same amounts are reported for every group.
 // @Tags subscriptions
 // @Produce json
 // @Param from query string true "Start month (MM-YYYY)"
 // @Param to query string true "End month (MM-YYYY)"
-// @Param user_id query string false "User ID (UUID)"
+// @Param user_id query string false "User ID (UUID); shared subscriptions count only this user's share"
 // @Param service_name query string false "Service name"
+// @Param tag query []string false "Tags, any of; repeated or comma-separated" collectionFormat(multi)
+// @Param currency query string false "Currency of the result (ISO 4217), default RUB"
+// @Param proration query string false "daily: charge monthly subscriptions for the days they cover in their first and last month"
+// @Param group_by query string false "Group totals by: service, user, tag or a combination, e.g. service,user"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/v1/subscriptions/total [get]
 func (h *Handler) Total(c *gin.Context) {
-	fromStr := strings.TrimSpace(c.Query("from"))
-	toStr := strings.TrimSpace(c.Query("to"))
-	if fromStr == "" || toStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "'from' and 'to' are required (MM-YYYY)"})
+	f, ok := parseTotalFilter(c)
+	if !ok {
 		return
 	}
 
-	from, err := domain.ParseMonthYear(fromStr)
+	if v := strings.TrimSpace(c.Query("group_by")); v != "" {
+		groupBy, err := domain.ParseGroupBy(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		f.GroupBy = groupBy
+		h.totalGrouped(c, f)
+		return
+	}
+
+	total, err := h.svc.TotalCost(c.Request.Context(), f)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'from' (expected MM-YYYY)"})
+		writeError(c, err)
 		return
 	}
-	to, err := domain.ParseMonthYear(toStr)
+
+	c.JSON(http.StatusOK, gin.H{
+		"gross":    total.Gross,
+		"discount": total.Discount,
+		"net":      total.Net,
+		"currency": f.TargetCurrency(),
+		"from":     domain.FormatMonthYear(f.From),
+		"to":       domain.FormatMonthYear(f.To),
+	})
+}
+
+func (h *Handler) totalGrouped(c *gin.Context, f domain.TotalFilter) {
+	items, err := h.svc.TotalCostGrouped(c.Request.Context(), f)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'to' (expected MM-YYYY)"})
+		writeError(c, err)
 		return
 	}
 
-	var userID *string
-	if v := strings.TrimSpace(c.Query("user_id")); v != "" {
-		userID = &v
+	var total domain.CostTotal
+	groups := make([]domain.GroupTotalDTO, 0, len(items))
+	for _, g := range items {
+		total.Gross += g.Gross
+		total.Discount += g.Discount
+		total.Net += g.Net
+		groups = append(groups, domain.ToGroupTotalDTO(g))
 	}
-	var serviceName *string
-	if v := strings.TrimSpace(c.Query("service_name")); v != "" {
-		serviceName = &v
+
+	c.JSON(http.StatusOK, gin.H{
+		"groups":   groups,
+		"gross":    total.Gross,
+		"discount": total.Discount,
+		"net":      total.Net,
+		"currency": f.TargetCurrency(),
+		"from":     domain.FormatMonthYear(f.From),
+		"to":       domain.FormatMonthYear(f.To),
+	})
+}
+
+// TotalBreakdown godoc
+// @Summary Monthly cost breakdown
+// @Description Cost of subscriptions for each month of the period
+// @Tags subscriptions
+// @Produce json
+// @Param from query string true "Start month (MM-YYYY)"
+// @Param to query string true "End month (MM-YYYY)"
+// @Param user_id query string false "User ID (UUID); shared subscriptions count only this user's share"
+// @Param service_name query string false "Service name"
+// @Param tag query []string false "Tags, any of; repeated or comma-separated" collectionFormat(multi)
+// @Param currency query string false "Currency of the result (ISO 4217), default RUB"
+// @Param proration query string false "daily: charge monthly subscriptions for the days they cover in their first and last month"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/subscriptions/total/breakdown [get]
+func (h *Handler) TotalBreakdown(c *gin.Context) {
+	f, ok := parseTotalFilter(c)
+	if !ok {
+		return
+	}
+
+	items, err := h.svc.TotalBreakdown(c.Request.Context(), f)
+	if err != nil {
+		writeError(c, err)
+		return
 	}
 
-	total, err := h.svc.TotalCost(c.Request.Context(), domain.TotalFilter{
-		UserID:      userID,
-		ServiceName: serviceName,
-		From:        from,
-		To:          to,
+	var total int64
+	months := make([]domain.MonthlyCostDTO, 0, len(items))
+	for _, m := range items {
+		total += m.Amount
+		months = append(months, domain.ToMonthlyCostDTO(m))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"months":   months,
+		"total":    total,
+		"currency": f.TargetCurrency(),
+		"from":     domain.FormatMonthYear(f.From),
+		"to":       domain.FormatMonthYear(f.To),
 	})
+}
+
+// Forecast godoc
+// @Summary Spend forecast
+// @Description Projected cost of subscriptions for each of the next months, starting with the current one.
+// @Description Takes into account end dates, scheduled price changes, free trials, pauses and discounts.
+// @Description Amounts in other currencies are converted with the latest loaded exchange rates.
+// @Tags subscriptions
+// @Produce json
+// @Param months query int false "Number of months, 1-60, default 12"
+// @Param user_id query string false "User ID (UUID); shared subscriptions count only this user's share"
+// @Param service_name query string false "Service name"
+// @Param tag query []string false "Tags, any of; repeated or comma-separated" collectionFormat(multi)
+// @Param currency query string false "Currency of the result (ISO 4217), default RUB"
+// @Param proration query string false "daily: charge monthly subscriptions for the days they cover in their first and last month"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/subscriptions/forecast [get]
+func (h *Handler) Forecast(c *gin.Context) {
+	f, ok := parseCostFilter(c)
+	if !ok {
+		return
+	}
+
+	months := domain.DefaultForecastMonths
+	if v := strings.TrimSpace(c.Query("months")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'months'"})
+			return
+		}
+		months = n
+	}
+
+	items, err := h.svc.Forecast(c.Request.Context(), f, months)
 	if err != nil {
 		writeError(c, err)
 		return
 	}
 
+	var total int64
+	out := make([]domain.MonthlyCostDTO, 0, len(items))
+	for _, m := range items {
+		total += m.Amount
+		out = append(out, domain.ToMonthlyCostDTO(m))
+	}
+
+	from, to := "", ""
+	if len(items) > 0 {
+		from = domain.FormatMonthYear(items[0].Month)
+		to = domain.FormatMonthYear(items[len(items)-1].Month)
+	}
 	c.JSON(http.StatusOK, gin.H{
+		"months":   out,
 		"total":    total,
-		"currency": "RUB",
-		"from":     fromStr,
-		"to":       toStr,
+		"currency": f.TargetCurrency(),
+		"from":     from,
+		"to":       to,
 	})
 }
 
+// Duplicates godoc
+// @Summary Overlapping subscriptions
+// @Description Pairs of subscriptions of the same user to the same service that are in effect on the same days,
+// @Description e.g. a service paid twice. 'from' and 'to' are the first and last common day; no 'to' means both have no end.
+// @Tags subscriptions
+// @Produce json
+// @Param user_id query string false "User ID (UUID)"
+// @Param service_name query string false "Service name"
+// @Success 200 {array} domain.SubscriptionOverlapDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/subscriptions/duplicates [get]
+func (h *Handler) Duplicates(c *gin.Context) {
+	var f domain.DuplicateFilter
+	if v := strings.TrimSpace(c.Query("user_id")); v != "" {
+		if _, err := uuid.Parse(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id (expected UUID)"})
+			return
+		}
+		f.UserID = &v
+	}
+	if v := strings.TrimSpace(c.Query("service_name")); v != "" {
+		f.ServiceName = &v
+	}
+
+	items, err := h.svc.Duplicates(c.Request.Context(), f)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	out := make([]domain.SubscriptionOverlapDTO, 0, len(items))
+	for _, o := range items {
+		out = append(out, domain.ToSubscriptionOverlapDTO(o))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
 // ---------- Helpers ----------
 
 func parseIDParam(c *gin.Context, name string) (int64, bool) {
@@ -366,6 +884,240 @@ func parseIDParam(c *gin.Context, name string) (int64, bool) {
 	return id, true
 }
 
+// parseUserIDParam читает user_id из пути; при ошибке сам пишет ответ 400 и возвращает false.
+func parseUserIDParam(c *gin.Context) (string, bool) {
+	userID := strings.TrimSpace(c.Param("user_id"))
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id (expected UUID)"})
+		return "", false
+	}
+	return userID, true
+}
+
+// parseOverlapMode разбирает параметр mode создания и изменения подписки:
+// strict — отклонять пересечение с другой подпиской того же пользователя на тот же сервис.
+// При ошибке сам пишет ответ 400 и возвращает false.
+func parseOverlapMode(c *gin.Context) (bool, bool) {
+	switch mode := strings.TrimSpace(c.Query("mode")); mode {
+	case "", "lenient":
+		return false, true
+	case "strict":
+		return true, true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'mode' (expected lenient or strict)"})
+		return false, false
+	}
+}
+
+// setETag выставляет ETag по версии подписки.
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// parseIfMatch разбирает заголовок If-Match. Отсутствие заголовка и "*"
+// означают «без проверки» (nil). Нераспознанное значение — ответ 412.
+func parseIfMatch(c *gin.Context) (*int64, bool) {
+	v := strings.TrimSpace(c.GetHeader("If-Match"))
+	if v == "" || v == "*" {
+		return nil, true
+	}
+	v = strings.TrimPrefix(v, "W/")
+	v = strings.Trim(v, `"`)
+	version, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || version <= 0 {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "invalid If-Match"})
+		return nil, false
+	}
+	return &version, true
+}
+
+// parseListFilter разбирает фильтры и сортировку списка подписок (без пагинации).
+// При ошибке сам пишет ответ 400 и возвращает false.
+func parseListFilter(c *gin.Context) (domain.ListFilter, bool) {
+	f := domain.ListFilter{
+		Limit:  domain.DefaultListLimit,
+		Offset: 0,
+	}
+
+	for _, raw := range c.QueryArray("user_id") {
+		for _, v := range strings.Split(raw, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			if _, err := uuid.Parse(v); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'user_id' (expected UUID)"})
+				return domain.ListFilter{}, false
+			}
+			f.UserIDs = append(f.UserIDs, v)
+		}
+	}
+	if v := strings.TrimSpace(c.Query("service_name")); v != "" {
+		f.ServiceName = &v
+	}
+	if v := strings.TrimSpace(c.Query("service_name_prefix")); v != "" {
+		f.ServiceNamePrefix = &v
+	}
+	f.Tags = queryTags(c)
+
+	if v := strings.TrimSpace(c.Query("from")); v != "" {
+		t, err := domain.ParseMonthYear(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'from' (expected MM-YYYY)"})
+			return domain.ListFilter{}, false
+		}
+		f.From = &t
+	}
+	if v := strings.TrimSpace(c.Query("to")); v != "" {
+		t, err := domain.ParseMonthYear(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'to' (expected MM-YYYY)"})
+			return domain.ListFilter{}, false
+		}
+		f.To = &t
+	}
+
+	if v := strings.TrimSpace(c.Query("price_min")); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'price_min'"})
+			return domain.ListFilter{}, false
+		}
+		f.PriceMin = &n
+	}
+	if v := strings.TrimSpace(c.Query("price_max")); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'price_max'"})
+			return domain.ListFilter{}, false
+		}
+		f.PriceMax = &n
+	}
+
+	if v := strings.TrimSpace(c.Query("status")); v != "" {
+		status, err := domain.ParseListStatus(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return domain.ListFilter{}, false
+		}
+		f.Status = status
+		f.StatusAt = domain.MonthStartUTC(time.Now())
+		if v := strings.TrimSpace(c.Query("status_at")); v != "" {
+			t, err := domain.ParseMonthYear(v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'status_at' (expected MM-YYYY)"})
+				return domain.ListFilter{}, false
+			}
+			f.StatusAt = t
+		}
+	}
+
+	sort, err := domain.ParseListSort(c.Query("sort"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return domain.ListFilter{}, false
+	}
+	f.Sort = sort
+
+	if v := strings.TrimSpace(c.Query("include_deleted")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'include_deleted'"})
+			return domain.ListFilter{}, false
+		}
+		f.IncludeDeleted = b
+	}
+	return f, true
+}
+
+// parseTotalFilter разбирает общие параметры эндпоинтов расчёта стоимости.
+// При ошибке сам пишет ответ 400 и возвращает false.
+func parseTotalFilter(c *gin.Context) (domain.TotalFilter, bool) {
+	fromStr := strings.TrimSpace(c.Query("from"))
+	toStr := strings.TrimSpace(c.Query("to"))
+	if fromStr == "" || toStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'from' and 'to' are required (MM-YYYY)"})
+		return domain.TotalFilter{}, false
+	}
+
+	from, err := domain.ParseMonthYear(fromStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'from' (expected MM-YYYY)"})
+		return domain.TotalFilter{}, false
+	}
+	to, err := domain.ParseMonthYear(toStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'to' (expected MM-YYYY)"})
+		return domain.TotalFilter{}, false
+	}
+
+	f, ok := parseCostFilter(c)
+	if !ok {
+		return domain.TotalFilter{}, false
+	}
+	f.From = from
+	f.To = to
+	return f, true
+}
+
+// parseCostFilter разбирает фильтры и параметры расчёта стоимости без периода.
+// При ошибке сам пишет ответ 400 и возвращает false.
+func parseCostFilter(c *gin.Context) (domain.TotalFilter, bool) {
+	var f domain.TotalFilter
+	if v := strings.TrimSpace(c.Query("user_id")); v != "" {
+		f.UserID = &v
+	}
+	if v := strings.TrimSpace(c.Query("service_name")); v != "" {
+		f.ServiceName = &v
+	}
+	f.Tags = queryTags(c)
+	currency, err := domain.ParseCurrency(c.Query("currency"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'currency' (expected ISO 4217 code)"})
+		return domain.TotalFilter{}, false
+	}
+	f.Currency = currency
+
+	proration, err := domain.ParseProration(strings.TrimSpace(c.Query("proration")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return domain.TotalFilter{}, false
+	}
+	f.Proration = proration
+	return f, true
+}
+
+// queryTags собирает параметр tag: можно повторять или перечислять через запятую.
+// Нормализует теги сервис.
+func queryTags(c *gin.Context) []string {
+	var tags []string
+	for _, raw := range c.QueryArray("tag") {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				tags = append(tags, v)
+			}
+		}
+	}
+	return tags
+}
+
+// stringList разбирает JSON-массив строк из map[string]any.
+func stringList(v any) ([]string, bool) {
+	items, ok := v.([]any)
+	if !ok {
+		return nil, false
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, s)
+	}
+	return out, true
+}
+
 func writeError(c *gin.Context, err error) {
 	switch {
 	case errors.Is(err, service.ErrNotFound):
@@ -373,6 +1125,19 @@ func writeError(c *gin.Context, err error) {
 	case errors.Is(err, service.ErrInvalidInput),
 		errors.Is(err, service.ErrInvalidDateRange):
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrMissingExchangeRate):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPreconditionFailed):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrIdempotencyInProgress),
+		errors.Is(err, service.ErrServiceNameTaken),
+		errors.Is(err, service.ErrServiceInUse),
+		errors.Is(err, service.ErrAlreadyPaused),
+		errors.Is(err, service.ErrNotPaused),
+		errors.Is(err, service.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 	default:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
 	}
@@ -0,0 +1,22 @@
+// Package notify содержит способы доставки уведомлений пользователям.
+package notify
+
+import (
+	"context"
+	"log"
+
+	"subscription_service/internal/domain"
+	"subscription_service/internal/service"
+)
+
+// Log пишет уведомления о превышении бюджета в лог приложения.
+// Используется, пока не подключена доставка пользователю (почта, вебхук и т. п.).
+type Log struct{}
+
+var _ service.BudgetNotifier = Log{}
+
+func (Log) NotifyBudgetExceeded(ctx context.Context, a domain.BudgetAlert) error {
+	log.Printf("budget %d of user %s exceeded for %s: spent %d of %d %s",
+		a.BudgetID, a.UserID, domain.FormatMonthYear(a.Month), a.Spent, a.Limit, a.Currency)
+	return nil
+}
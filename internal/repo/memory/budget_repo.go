@@ -0,0 +1,178 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"subscription_service/internal/domain"
+	"subscription_service/internal/service"
+)
+
+// BudgetRepo хранит бюджеты и записанные превышения в памяти процесса.
+type BudgetRepo struct {
+	mu          sync.RWMutex
+	nextID      int64
+	items       map[int64]domain.Budget
+	nextAlertID int64
+	alerts      []domain.BudgetAlert // в порядке записи
+}
+
+func NewBudgetRepo() *BudgetRepo {
+	return &BudgetRepo{
+		nextID:      1,
+		items:       make(map[int64]domain.Budget),
+		nextAlertID: 1,
+	}
+}
+
+var _ service.BudgetRepository = (*BudgetRepo)(nil)
+
+func (r *BudgetRepo) Create(ctx context.Context, b domain.Budget) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	b.ID = r.nextID
+	b.CreatedAt = now
+	b.UpdatedAt = now
+	r.nextID++
+
+	r.items[b.ID] = cloneBudget(b)
+	return b.ID, nil
+}
+
+func (r *BudgetRepo) GetByID(ctx context.Context, id int64) (*domain.Budget, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	b, ok := r.items[id]
+	if !ok {
+		return nil, nil
+	}
+	b = cloneBudget(b)
+	return &b, nil
+}
+
+func (r *BudgetRepo) Update(ctx context.Context, b domain.Budget) (*domain.Budget, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.items[b.ID]
+	if !ok {
+		return nil, nil
+	}
+	b.UserID = existing.UserID
+	b.CreatedAt = existing.CreatedAt
+	b.UpdatedAt = time.Now().UTC()
+
+	r.items[b.ID] = cloneBudget(b)
+	b = cloneBudget(b)
+	return &b, nil
+}
+
+// Delete удаляет бюджет вместе с его превышениями, как ON DELETE CASCADE.
+func (r *BudgetRepo) Delete(ctx context.Context, id int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.items[id]; !ok {
+		return false, nil
+	}
+	delete(r.items, id)
+
+	kept := r.alerts[:0]
+	for _, a := range r.alerts {
+		if a.BudgetID != id {
+			kept = append(kept, a)
+		}
+	}
+	r.alerts = kept
+	return true, nil
+}
+
+func (r *BudgetRepo) List(ctx context.Context, userID string) ([]domain.Budget, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	items := make([]domain.Budget, 0, len(r.items))
+	for _, b := range r.items {
+		if userID != "" && b.UserID != userID {
+			continue
+		}
+		items = append(items, cloneBudget(b))
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items, nil
+}
+
+func (r *BudgetRepo) AddAlert(ctx context.Context, o domain.BudgetOverrun) (*domain.BudgetAlert, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// как UNIQUE (budget_id, month)
+	for _, a := range r.alerts {
+		if a.BudgetID == o.BudgetID && a.Month.Equal(o.Month) {
+			return nil, nil
+		}
+	}
+
+	a := domain.BudgetAlert{ID: r.nextAlertID, BudgetOverrun: o, CreatedAt: time.Now().UTC()}
+	r.nextAlertID++
+	r.alerts = append(r.alerts, a)
+	return &a, nil
+}
+
+func (r *BudgetRepo) ListAlerts(ctx context.Context, f domain.BudgetAlertFilter) ([]domain.BudgetAlert, error) {
+	limit := f.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var items []domain.BudgetAlert
+	skipped := 0
+	for i := len(r.alerts) - 1; i >= 0 && len(items) < limit; i-- {
+		a := r.alerts[i]
+		if f.UserID != "" && a.UserID != f.UserID {
+			continue
+		}
+		if f.Pending && a.NotifiedAt != nil {
+			continue
+		}
+		if skipped < f.Offset {
+			skipped++
+			continue
+		}
+		items = append(items, a)
+	}
+	return items, nil
+}
+
+func (r *BudgetRepo) MarkNotified(ctx context.Context, alertID int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.alerts {
+		if r.alerts[i].ID == alertID {
+			at := at.UTC()
+			r.alerts[i].NotifiedAt = &at
+			return nil
+		}
+	}
+	return nil
+}
+
+func cloneBudget(b domain.Budget) domain.Budget {
+	if b.Tag != nil {
+		v := *b.Tag
+		b.Tag = &v
+	}
+	return b
+}
@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"subscription_service/internal/domain"
+)
+
+func TestBudgetAlerts_UniquePerMonthPendingAndCascade(t *testing.T) {
+	repo := NewBudgetRepo()
+	ctx := context.Background()
+
+	id, err := repo.Create(ctx, domain.Budget{UserID: testUserID, Amount: 1000, Currency: "RUB"})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	o := domain.BudgetOverrun{BudgetID: id, UserID: testUserID, Month: month(t, "07-2025"), Limit: 1000, Spent: 1200, Currency: "RUB"}
+	first, err := repo.AddAlert(ctx, o)
+	if err != nil || first == nil {
+		t.Fatalf("expected alert, got %+v, %v", first, err)
+	}
+	if again, err := repo.AddAlert(ctx, o); err != nil || again != nil {
+		t.Fatalf("expected no second alert for the same month, got %+v, %v", again, err)
+	}
+	o.Month = month(t, "08-2025")
+	if _, err := repo.AddAlert(ctx, o); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	if err := repo.MarkNotified(ctx, first.ID, time.Now()); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	pending, _ := repo.ListAlerts(ctx, domain.BudgetAlertFilter{Pending: true})
+	if len(pending) != 1 || domain.FormatMonthYear(pending[0].Month) != "08-2025" {
+		t.Fatalf("expected only 08-2025 pending, got %+v", pending)
+	}
+	all, _ := repo.ListAlerts(ctx, domain.BudgetAlertFilter{UserID: testUserID})
+	if len(all) != 2 || all[0].ID <= all[1].ID {
+		t.Fatalf("expected 2 alerts newest first, got %+v", all)
+	}
+
+	if ok, _ := repo.Delete(ctx, id); !ok {
+		t.Fatalf("expected budget deleted")
+	}
+	if all, _ := repo.ListAlerts(ctx, domain.BudgetAlertFilter{UserID: testUserID}); len(all) != 0 {
+		t.Fatalf("expected alerts deleted with the budget, got %+v", all)
+	}
+}
@@ -0,0 +1,228 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"subscription_service/internal/domain"
+	"subscription_service/internal/service"
+)
+
+// CatalogRepo хранит каталог сервисов в памяти процесса.
+// Переименование и проверку ссылок делает через связанный SubscriptionRepo.
+type CatalogRepo struct {
+	mu     sync.RWMutex
+	nextID int64
+	items  map[int64]domain.Service
+	keys   map[string]int64 // ключ имени или алиаса -> id записи
+
+	subs *SubscriptionRepo
+}
+
+func NewCatalogRepo(subs *SubscriptionRepo) *CatalogRepo {
+	r := &CatalogRepo{
+		nextID: 1,
+		items:  make(map[int64]domain.Service),
+		keys:   make(map[string]int64),
+		subs:   subs,
+	}
+	if subs != nil {
+		subs.catalog = r
+	}
+	return r
+}
+
+var _ service.CatalogRepository = (*CatalogRepo)(nil)
+
+func (r *CatalogRepo) Create(ctx context.Context, s domain.Service) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.taken(s, 0) {
+		return 0, service.ErrServiceNameTaken
+	}
+	return r.create(s), nil
+}
+
+// create вызывается под r.mu.
+func (r *CatalogRepo) create(s domain.Service) int64 {
+	now := time.Now().UTC()
+	s.ID = r.nextID
+	s.CreatedAt = now
+	s.UpdatedAt = now
+	r.nextID++
+
+	r.items[s.ID] = cloneService(s)
+	r.index(s)
+	return s.ID
+}
+
+func (r *CatalogRepo) GetByID(ctx context.Context, id int64) (*domain.Service, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.items[id]
+	if !ok {
+		return nil, nil
+	}
+	s = cloneService(s)
+	return &s, nil
+}
+
+func (r *CatalogRepo) Update(ctx context.Context, s domain.Service) (*domain.Service, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.items[s.ID]
+	if !ok {
+		return nil, nil
+	}
+	if r.taken(s, s.ID) {
+		return nil, service.ErrServiceNameTaken
+	}
+
+	if r.subs != nil {
+		if err := r.subs.renameService(ctx, s.ID, s.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	r.unindex(existing)
+	s.CreatedAt = existing.CreatedAt
+	s.UpdatedAt = time.Now().UTC()
+	r.items[s.ID] = cloneService(s)
+	r.index(s)
+
+	updated := cloneService(s)
+	return &updated, nil
+}
+
+func (r *CatalogRepo) Delete(ctx context.Context, id int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.items[id]
+	if !ok {
+		return false, nil
+	}
+	if r.subs != nil && r.subs.usesService(id) {
+		return false, service.ErrServiceInUse
+	}
+	r.unindex(existing)
+	delete(r.items, id)
+	return true, nil
+}
+
+func (r *CatalogRepo) List(ctx context.Context, f domain.ServiceFilter) ([]domain.Service, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	items := make([]domain.Service, 0, len(r.items))
+	for _, s := range r.items {
+		if f.Category != nil && *f.Category != "" && (s.Category == nil || !strings.EqualFold(*s.Category, *f.Category)) {
+			continue
+		}
+		items = append(items, cloneService(s))
+	}
+	slices.SortFunc(items, func(a, b domain.Service) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return items, nil
+}
+
+func (r *CatalogRepo) Resolve(ctx context.Context, name string) (*domain.Service, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.resolve(name), nil
+}
+
+func (r *CatalogRepo) Ensure(ctx context.Context, name string) (*domain.Service, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s := r.resolve(name); s != nil {
+		return s, nil
+	}
+	clean := domain.CleanServiceName(name)
+	if clean == "" {
+		return nil, nil
+	}
+	id := r.create(domain.Service{Name: clean})
+	s := cloneService(r.items[id])
+	return &s, nil
+}
+
+// plan — Ensure без сохранения для SubscriptionRepo.CreateBatch: pending — записи, уже
+// запланированные в этом батче. Новая запись получает id, который ей затем выдаст create,
+// если создавать pending и её по порядку, не отпуская r.mu. ID = 0 — имя пустое.
+func (r *CatalogRepo) plan(name string, pending []domain.Service) (domain.Service, bool) {
+	if s := r.resolve(name); s != nil {
+		return *s, false
+	}
+	key := domain.ServiceNameKey(name)
+	for _, s := range pending {
+		if domain.ServiceNameKey(s.Name) == key {
+			return s, false
+		}
+	}
+	clean := domain.CleanServiceName(name)
+	if clean == "" {
+		return domain.Service{}, false
+	}
+	return domain.Service{ID: r.nextID + int64(len(pending)), Name: clean}, true
+}
+
+// resolve вызывается под r.mu.
+func (r *CatalogRepo) resolve(name string) *domain.Service {
+	id, ok := r.keys[domain.ServiceNameKey(name)]
+	if !ok {
+		return nil
+	}
+	s := cloneService(r.items[id])
+	return &s
+}
+
+// taken проверяет, заняты ли имя или алиасы s другой записью; вызывается под r.mu.
+func (r *CatalogRepo) taken(s domain.Service, selfID int64) bool {
+	for _, name := range append([]string{s.Name}, s.Aliases...) {
+		if id, ok := r.keys[domain.ServiceNameKey(name)]; ok && id != selfID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *CatalogRepo) index(s domain.Service) {
+	r.keys[domain.ServiceNameKey(s.Name)] = s.ID
+	for _, a := range s.Aliases {
+		r.keys[domain.ServiceNameKey(a)] = s.ID
+	}
+}
+
+func (r *CatalogRepo) unindex(s domain.Service) {
+	delete(r.keys, domain.ServiceNameKey(s.Name))
+	for _, a := range s.Aliases {
+		delete(r.keys, domain.ServiceNameKey(a))
+	}
+}
+
+func cloneService(s domain.Service) domain.Service {
+	s.Aliases = slices.Clone(s.Aliases)
+	if s.Category != nil {
+		category := *s.Category
+		s.Category = &category
+	}
+	if s.DefaultPrice != nil {
+		price := *s.DefaultPrice
+		s.DefaultPrice = &price
+	}
+	return s
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"subscription_service/internal/domain"
+	"subscription_service/internal/service"
+)
+
+func TestCatalog_AliasesRenameAndDeleteInUse(t *testing.T) {
+	subs := NewSubscriptionRepo(NewExchangeRateRepo())
+	catalog := NewCatalogRepo(subs)
+	ctx := context.Background()
+
+	id, err := catalog.Create(ctx, domain.Service{Name: "YouTube Premium", Aliases: []string{"YT Premium"}})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if _, err := catalog.Create(ctx, domain.Service{Name: "yt  premium"}); !errors.Is(err, service.ErrServiceNameTaken) {
+		t.Fatalf("expected ErrServiceNameTaken, got %v", err)
+	}
+
+	got, err := catalog.Resolve(ctx, " yt PREMIUM ")
+	if err != nil || got == nil || got.ID != id {
+		t.Fatalf("expected service %d by alias, got %+v, %v", id, got, err)
+	}
+
+	subID, err := subs.Create(ctx, domain.Subscription{
+		ServiceName: "YouTube Premium",
+		ServiceID:   &id,
+		Price:       300,
+		UserID:      testUserID,
+		StartDate:   month(t, "07-2025"),
+	}, false)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	got.Name = "YouTube"
+	if _, err := catalog.Update(ctx, *got); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	sub, _ := subs.GetByID(ctx, subID)
+	if sub.ServiceName != "YouTube" || sub.Version != 2 {
+		t.Fatalf("expected renamed subscription with version 2, got %q v%d", sub.ServiceName, sub.Version)
+	}
+	events, _ := subs.ListEvents(ctx, domain.EventFilter{SubscriptionID: subID})
+	if len(events) != 2 || events[0].Action != domain.EventUpdate || events[0].Before == nil || events[0].After == nil {
+		t.Fatalf("expected update event for the rename, got %+v", events)
+	}
+	if old, _ := catalog.Resolve(ctx, "YouTube Premium"); old != nil {
+		t.Fatalf("expected old name to be released, got %+v", old)
+	}
+
+	if _, err := catalog.Delete(ctx, id); !errors.Is(err, service.ErrServiceInUse) {
+		t.Fatalf("expected ErrServiceInUse, got %v", err)
+	}
+}
+
+func TestCreateBatch_EnsuresCatalogEntries(t *testing.T) {
+	subs := NewSubscriptionRepo(NewExchangeRateRepo())
+	catalog := NewCatalogRepo(subs)
+	ctx := context.Background()
+
+	netflix, _ := catalog.Create(ctx, domain.Service{Name: "Netflix"})
+	ids, err := subs.CreateBatch(ctx, []domain.Subscription{
+		{ServiceName: "Kion", Price: 200, UserID: testUserID, StartDate: month(t, "07-2025")},
+		{ServiceName: "kion", Price: 200, UserID: testUserID, StartDate: month(t, "08-2025")},
+		{ServiceName: "Netflix", Price: 400, UserID: testUserID, StartDate: month(t, "07-2025")},
+	})
+	if err != nil || len(ids) != 3 {
+		t.Fatalf("expected 3 ids, got %v, %v", ids, err)
+	}
+
+	kion, _ := catalog.Resolve(ctx, "KION")
+	if kion == nil {
+		t.Fatal("expected catalog entry for a new name")
+	}
+	for i, want := range []int64{kion.ID, kion.ID, netflix} {
+		s, _ := subs.GetByID(ctx, ids[i])
+		if s.ServiceID == nil || *s.ServiceID != want {
+			t.Fatalf("subscription %d: expected service %d, got %v", ids[i], want, s.ServiceID)
+		}
+	}
+	if s, _ := subs.GetByID(ctx, ids[1]); s.ServiceName != "Kion" {
+		t.Fatalf("expected canonical name, got %q", s.ServiceName)
+	}
+	if items, _ := catalog.List(ctx, domain.ServiceFilter{}); len(items) != 2 {
+		t.Fatalf("expected 2 catalog entries, got %+v", items)
+	}
+}
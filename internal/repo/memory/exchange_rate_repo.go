@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"subscription_service/internal/domain"
+	"subscription_service/internal/service"
+)
+
+type rateKey struct {
+	month    time.Time
+	currency string
+}
+
+type ExchangeRateRepo struct {
+	mu    sync.RWMutex
+	rates map[rateKey]float64
+}
+
+func NewExchangeRateRepo() *ExchangeRateRepo {
+	return &ExchangeRateRepo{rates: make(map[rateKey]float64)}
+}
+
+var _ service.ExchangeRateRepository = (*ExchangeRateRepo)(nil)
+
+func (r *ExchangeRateRepo) Upsert(ctx context.Context, rates []domain.ExchangeRate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rate := range rates {
+		r.rates[rateKey{month: domain.MonthStartUTC(rate.Month), currency: rate.Currency}] = rate.Rate
+	}
+	return nil
+}
+
+func (r *ExchangeRateRepo) List(ctx context.Context, f domain.ExchangeRateFilter) ([]domain.ExchangeRate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	items := make([]domain.ExchangeRate, 0, len(r.rates))
+	for k, rate := range r.rates {
+		if f.Currency != nil && *f.Currency != "" && k.currency != *f.Currency {
+			continue
+		}
+		if f.From != nil && k.month.Before(domain.MonthStartUTC(*f.From)) {
+			continue
+		}
+		if f.To != nil && k.month.After(domain.MonthStartUTC(*f.To)) {
+			continue
+		}
+		items = append(items, domain.ExchangeRate{Month: k.month, Currency: k.currency, Rate: rate})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].Month.Equal(items[j].Month) {
+			return items[i].Month.Before(items[j].Month)
+		}
+		return items[i].Currency < items[j].Currency
+	})
+	return items, nil
+}
+
+// convert возвращает множитель для перевода суммы из src в dst в месяце m.
+// С latest берётся последний курс не позже m (см. domain.TotalFilter.LatestRates).
+// Повторяет CASE по exchange_rates в postgres.SubscriptionRepo.
+func (r *ExchangeRateRepo) convert(m time.Time, src, dst string, latest bool) (float64, bool) {
+	if src == dst {
+		return 1, true
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rateOf := func(currency string) (float64, bool) {
+		if currency == domain.BaseCurrency {
+			return 1, true
+		}
+		if !latest {
+			rate, ok := r.rates[rateKey{month: m, currency: currency}]
+			return rate, ok
+		}
+		var (
+			rate  float64
+			month time.Time
+			found bool
+		)
+		for k, v := range r.rates {
+			if k.currency != currency || k.month.After(m) || (found && !k.month.After(month)) {
+				continue
+			}
+			rate, month, found = v, k.month, true
+		}
+		return rate, found
+	}
+
+	srcRate, ok := rateOf(src)
+	if !ok {
+		return 0, false
+	}
+	dstRate, ok := rateOf(dst)
+	if !ok {
+		return 0, false
+	}
+	return srcRate / dstRate, true
+}
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"subscription_service/internal/domain"
+	"subscription_service/internal/service"
+)
+
+type IdempotencyRepo struct {
+	mu      sync.Mutex
+	records map[string]domain.IdempotencyRecord
+	now     func() time.Time
+}
+
+func NewIdempotencyRepo() *IdempotencyRepo {
+	return &IdempotencyRepo{records: make(map[string]domain.IdempotencyRecord), now: time.Now}
+}
+
+var _ service.IdempotencyRepository = (*IdempotencyRepo)(nil)
+
+func (r *IdempotencyRepo) Reserve(ctx context.Context, key, requestHash string, expiresAt time.Time) (*domain.IdempotencyRecord, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rec, ok := r.records[key]; ok && rec.ExpiresAt.After(r.now()) {
+		rec.Headers = maps.Clone(rec.Headers)
+		return &rec, false, nil
+	}
+	r.records[key] = domain.IdempotencyRecord{Key: key, RequestHash: requestHash, ExpiresAt: expiresAt}
+	return nil, true, nil
+}
+
+func (r *IdempotencyRepo) Complete(ctx context.Context, c domain.IdempotencyRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.records[c.Key]
+	if !ok || rec.Completed() || rec.RequestHash != c.RequestHash {
+		return nil
+	}
+	status := *c.StatusCode
+	rec.StatusCode = &status
+	rec.Headers = maps.Clone(c.Headers)
+	rec.Response = append([]byte(nil), c.Response...)
+	rec.ExpiresAt = c.ExpiresAt
+	r.records[c.Key] = rec
+	return nil
+}
+
+func (r *IdempotencyRepo) Release(ctx context.Context, key, requestHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rec, ok := r.records[key]; ok && rec.RequestHash == requestHash && !rec.Completed() {
+		delete(r.records, key)
+	}
+	return nil
+}
+
+func (r *IdempotencyRepo) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for key, rec := range r.records {
+		if !rec.ExpiresAt.After(now) {
+			delete(r.records, key)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"subscription_service/internal/domain"
+)
+
+// recordEvent — аналог postgres insertEvent. Вызывается под r.mu вместе с самим изменением.
+func (r *SubscriptionRepo) recordEvent(ctx context.Context, subscriptionID int64, action domain.EventAction, before, after *domain.Subscription) error {
+	e, err := newEvent(ctx, subscriptionID, action, before, after)
+	if err != nil {
+		return err
+	}
+	r.appendEvent(e)
+	return nil
+}
+
+// appendEvent добавляет событие в журнал и присваивает ему id; вызывается под r.mu.
+func (r *SubscriptionRepo) appendEvent(e domain.SubscriptionEvent) {
+	e.ID = int64(len(r.events) + 1)
+	r.events = append(r.events, e)
+}
+
+// newEvent готовит событие, не записывая его: id присваивает appendEvent.
+func newEvent(ctx context.Context, subscriptionID int64, action domain.EventAction, before, after *domain.Subscription) (domain.SubscriptionEvent, error) {
+	beforeJSON, err := domain.Snapshot(before)
+	if err != nil {
+		return domain.SubscriptionEvent{}, err
+	}
+	afterJSON, err := domain.Snapshot(after)
+	if err != nil {
+		return domain.SubscriptionEvent{}, err
+	}
+
+	e := domain.SubscriptionEvent{
+		SubscriptionID: subscriptionID,
+		Action:         action,
+		Before:         beforeJSON,
+		After:          afterJSON,
+		CreatedAt:      time.Now().UTC(),
+	}
+	if v := domain.RequestIDFromContext(ctx); v != "" {
+		e.RequestID = &v
+	}
+	if v := domain.ActorFromContext(ctx); v != "" {
+		e.Actor = &v
+	}
+	return e, nil
+}
+
+func (r *SubscriptionRepo) ListEvents(ctx context.Context, f domain.EventFilter) ([]domain.SubscriptionEvent, error) {
+	limit := f.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var items []domain.SubscriptionEvent
+	skipped := 0
+	for i := len(r.events) - 1; i >= 0 && len(items) < limit; i-- {
+		e := r.events[i]
+		if e.SubscriptionID != f.SubscriptionID {
+			continue
+		}
+		if skipped < f.Offset {
+			skipped++
+			continue
+		}
+		items = append(items, e)
+	}
+	return items, nil
+}
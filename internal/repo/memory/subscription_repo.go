@@ -0,0 +1,886 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"subscription_service/internal/domain"
+	"subscription_service/internal/service"
+)
+
+// SubscriptionRepo хранит подписки в памяти процесса.
+// Семантика повторяет postgres.SubscriptionRepo, поэтому репозиторий
+// подходит и для тестов, и для локального запуска без базы.
+type SubscriptionRepo struct {
+	mu             sync.RWMutex
+	nextID         int64
+	items          map[int64]domain.Subscription
+	prices         map[int64][]domain.PriceChange // отсортированы по EffectiveFrom
+	events         []domain.SubscriptionEvent     // журнал аудита, id = позиция + 1
+	discounts      map[int64][]domain.Discount    // отсортированы по From, затем по ID
+	nextDiscountID int64
+
+	rates   *ExchangeRateRepo
+	catalog *CatalogRepo // задаёт NewCatalogRepo; нужен CreateBatch
+}
+
+func NewSubscriptionRepo(rates *ExchangeRateRepo) *SubscriptionRepo {
+	return &SubscriptionRepo{
+		nextID:         1,
+		items:          make(map[int64]domain.Subscription),
+		prices:         make(map[int64][]domain.PriceChange),
+		discounts:      make(map[int64][]domain.Discount),
+		nextDiscountID: 1,
+		rates:          rates,
+	}
+}
+
+var _ service.SubscriptionRepository = (*SubscriptionRepo)(nil)
+
+func (r *SubscriptionRepo) Create(ctx context.Context, s domain.Subscription, rejectOverlaps bool) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rejectOverlaps {
+		if err := r.checkOverlaps(s); err != nil {
+			return 0, err
+		}
+	}
+	s, e, err := r.prepareCreate(ctx, s, r.nextID)
+	if err != nil {
+		return 0, err
+	}
+	r.insert(s, e)
+	return s.ID, nil
+}
+
+// CreateBatch, как и postgres, сохраняет всё или ничего: подписки и их события готовятся
+// заранее, и r.items меняется, только когда ошибок уже быть не может.
+// Подписки без ServiceID привязываются к каталогу, новые имена заводятся в нём вместе со вставкой.
+func (r *SubscriptionRepo) CreateBatch(ctx context.Context, subs []domain.Subscription) ([]int64, error) {
+	// каталог блокируется раньше подписок, как в CatalogRepo.Update
+	if r.catalog != nil {
+		r.catalog.mu.Lock()
+		defer r.catalog.mu.Unlock()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var services []domain.Service
+	prepared := make([]domain.Subscription, 0, len(subs))
+	events := make([]domain.SubscriptionEvent, 0, len(subs))
+	for i, s := range subs {
+		if s.ServiceID == nil && r.catalog != nil {
+			svc, isNew := r.catalog.plan(s.ServiceName, services)
+			if isNew {
+				services = append(services, svc)
+			}
+			if svc.ID != 0 {
+				s.ServiceID = &svc.ID
+				s.ServiceName = svc.Name
+			}
+		}
+		s, e, err := r.prepareCreate(ctx, s, r.nextID+int64(i))
+		if err != nil {
+			return nil, err
+		}
+		prepared = append(prepared, s)
+		events = append(events, e)
+	}
+
+	for _, svc := range services {
+		r.catalog.create(svc)
+	}
+	ids := make([]int64, 0, len(prepared))
+	for i, s := range prepared {
+		r.insert(s, events[i])
+		ids = append(ids, s.ID)
+	}
+	return ids, nil
+}
+
+// prepareCreate заполняет служебные поля новой подписки с этим id и готовит событие создания,
+// ничего не сохраняя. Вызывается под r.mu.
+func (r *SubscriptionRepo) prepareCreate(ctx context.Context, s domain.Subscription, id int64) (domain.Subscription, domain.SubscriptionEvent, error) {
+	now := time.Now().UTC()
+	s.ID = id
+	s.CreatedAt = now
+	s.UpdatedAt = now
+	s.Version = 1
+
+	e, err := newEvent(ctx, s.ID, domain.EventCreate, nil, &s)
+	if err != nil {
+		return domain.Subscription{}, domain.SubscriptionEvent{}, err
+	}
+	return s, e, nil
+}
+
+// insert сохраняет подписку, подготовленную prepareCreate, вместе с её событием; вызывается под r.mu.
+func (r *SubscriptionRepo) insert(s domain.Subscription, e domain.SubscriptionEvent) {
+	r.nextID = max(r.nextID, s.ID+1)
+	r.appendEvent(e)
+	r.items[s.ID] = clone(s)
+	r.prices[s.ID] = []domain.PriceChange{{
+		SubscriptionID: s.ID,
+		EffectiveFrom:  domain.MonthStartUTC(s.StartDate),
+		Price:          s.Price,
+	}}
+}
+
+func (r *SubscriptionRepo) GetByID(ctx context.Context, id int64) (*domain.Subscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.items[id]
+	if !ok || s.DeletedAt != nil {
+		return nil, nil
+	}
+	s = clone(s)
+	return &s, nil
+}
+
+func (r *SubscriptionRepo) Update(ctx context.Context, s domain.Subscription, price *domain.PriceChange, rejectOverlaps bool) (*domain.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.items[s.ID]
+	if !ok || existing.DeletedAt != nil {
+		return nil, nil
+	}
+	if existing.Version != s.Version {
+		return nil, service.ErrPreconditionFailed
+	}
+	if rejectOverlaps {
+		if err := r.checkOverlaps(s); err != nil {
+			return nil, err
+		}
+	}
+
+	s.CreatedAt = existing.CreatedAt
+	s.UpdatedAt = time.Now().UTC()
+	s.Version++
+	if err := r.recordEvent(ctx, s.ID, domain.EventUpdate, &existing, &s); err != nil {
+		return nil, err
+	}
+	r.items[s.ID] = clone(s)
+	r.updatePrices(s, price)
+
+	updated := clone(s)
+	return &updated, nil
+}
+
+// updatePrices повторяет работу с subscription_prices из postgres.SubscriptionRepo.Update.
+// Вызывается под r.mu.
+func (r *SubscriptionRepo) updatePrices(s domain.Subscription, price *domain.PriceChange) {
+	history := r.prices[s.ID]
+
+	if price != nil {
+		from := domain.MonthStartUTC(price.EffectiveFrom)
+		kept := make([]domain.PriceChange, 0, len(history)+1)
+		for _, p := range history {
+			if p.EffectiveFrom.Before(from) {
+				kept = append(kept, p)
+			}
+		}
+		history = append(kept, domain.PriceChange{SubscriptionID: s.ID, EffectiveFrom: from, Price: price.Price})
+	}
+
+	start := domain.MonthStartUTC(s.StartDate)
+	if len(history) > 0 && history[0].EffectiveFrom.After(start) {
+		history[0].EffectiveFrom = start
+	}
+
+	r.prices[s.ID] = history
+}
+
+func (r *SubscriptionRepo) ListPrices(ctx context.Context, id int64) ([]domain.PriceChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]domain.PriceChange(nil), r.prices[id]...), nil
+}
+
+func (r *SubscriptionRepo) AddDiscount(ctx context.Context, d domain.Discount) (domain.Discount, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d.ID = r.nextDiscountID
+	r.nextDiscountID++
+	d.CreatedAt = time.Now().UTC()
+
+	items := append(r.discounts[d.SubscriptionID], d)
+	slices.SortFunc(items, func(a, b domain.Discount) int {
+		return cmp.Or(a.From.Compare(b.From), cmp.Compare(a.ID, b.ID))
+	})
+	r.discounts[d.SubscriptionID] = items
+	return d, nil
+}
+
+func (r *SubscriptionRepo) ListDiscounts(ctx context.Context, id int64) ([]domain.Discount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.discounts[id]), nil
+}
+
+func (r *SubscriptionRepo) DeleteDiscount(ctx context.Context, id, discountID int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	items := r.discounts[id]
+	i := slices.IndexFunc(items, func(d domain.Discount) bool { return d.ID == discountID })
+	if i < 0 {
+		return false, nil
+	}
+	r.discounts[id] = slices.Delete(slices.Clone(items), i, i+1)
+	return true, nil
+}
+
+// Delete помечает подписку удалённой, как и postgres.SubscriptionRepo.
+func (r *SubscriptionRepo) Delete(ctx context.Context, id int64, version int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.items[id]
+	if !ok || existing.DeletedAt != nil {
+		return false, nil
+	}
+	if version != 0 && existing.Version != version {
+		return false, service.ErrPreconditionFailed
+	}
+	if err := r.recordEvent(ctx, id, domain.EventDelete, &existing, nil); err != nil {
+		return false, err
+	}
+
+	now := time.Now().UTC()
+	existing.DeletedAt = &now
+	existing.UpdatedAt = now
+	existing.Version++
+	r.items[id] = existing
+	return true, nil
+}
+
+func (r *SubscriptionRepo) Restore(ctx context.Context, id int64) (*domain.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.items[id]
+	if !ok || existing.DeletedAt == nil {
+		return nil, nil
+	}
+
+	existing.DeletedAt = nil
+	existing.UpdatedAt = time.Now().UTC()
+	existing.Version++
+	if err := r.recordEvent(ctx, id, domain.EventRestore, nil, &existing); err != nil {
+		return nil, err
+	}
+	r.items[id] = existing
+
+	restored := clone(existing)
+	return &restored, nil
+}
+
+func (r *SubscriptionRepo) Pause(ctx context.Context, id int64, p domain.Pause) (*domain.Subscription, error) {
+	return r.changePauses(ctx, id, domain.EventPause, func(s *domain.Subscription) error {
+		if slices.ContainsFunc(s.Pauses, p.Overlaps) {
+			return service.ErrAlreadyPaused
+		}
+		s.Pauses = append(s.Pauses, p)
+		slices.SortFunc(s.Pauses, func(a, b domain.Pause) int { return a.From.Compare(b.From) })
+		return nil
+	})
+}
+
+func (r *SubscriptionRepo) Resume(ctx context.Context, id int64, at time.Time) (*domain.Subscription, error) {
+	return r.changePauses(ctx, id, domain.EventResume, func(s *domain.Subscription) error {
+		i := s.PauseAt(at)
+		if i < 0 {
+			return service.ErrNotPaused
+		}
+		if resumed, ok := s.Pauses[i].ResumedAt(at); ok {
+			s.Pauses[i] = resumed
+		} else {
+			s.Pauses = slices.Delete(s.Pauses, i, i+1)
+		}
+		return nil
+	})
+}
+
+// changePauses повторяет postgres.SubscriptionRepo.changePauses: change правит паузы в копии подписки,
+// версия увеличивается, событие пишется вместе с изменением.
+func (r *SubscriptionRepo) changePauses(ctx context.Context, id int64, action domain.EventAction, change func(s *domain.Subscription) error) (*domain.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.items[id]
+	if !ok || existing.DeletedAt != nil {
+		return nil, nil
+	}
+
+	s := clone(existing)
+	if err := change(&s); err != nil {
+		return nil, err
+	}
+	s.UpdatedAt = time.Now().UTC()
+	s.Version++
+	if err := r.recordEvent(ctx, id, action, &existing, &s); err != nil {
+		return nil, err
+	}
+	r.items[id] = clone(s)
+
+	updated := clone(s)
+	return &updated, nil
+}
+
+func (r *SubscriptionRepo) Purge(ctx context.Context, olderThan time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	for id, s := range r.items {
+		if s.DeletedAt == nil || !s.DeletedAt.Before(olderThan) {
+			continue
+		}
+		if err := r.recordEvent(ctx, id, domain.EventPurge, nil, nil); err != nil {
+			return purged, err
+		}
+		delete(r.items, id)
+		delete(r.prices, id)
+		delete(r.discounts, id)
+		purged++
+	}
+	return purged, nil
+}
+
+func (r *SubscriptionRepo) List(ctx context.Context, f domain.ListFilter) ([]domain.Subscription, error) {
+	limit := f.Limit
+	if limit <= 0 {
+		limit = domain.DefaultListLimit
+	}
+	if f.Offset < 0 || f.After != nil {
+		f.Offset = 0
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matched := make([]domain.Subscription, 0, len(r.items))
+	for _, s := range r.items {
+		if matchList(s, f) {
+			matched = append(matched, s)
+		}
+	}
+	slices.SortFunc(matched, f.Sort.Compare)
+
+	if f.Offset >= len(matched) {
+		return nil, nil
+	}
+	matched = matched[f.Offset:]
+	if len(matched) > limit {
+		matched = matched[:limit]
+	}
+
+	items := make([]domain.Subscription, 0, len(matched))
+	for _, s := range matched {
+		items = append(items, clone(s))
+	}
+	return items, nil
+}
+
+// Export вызывает fn для каждой подписки под фильтром в порядке f.Sort.
+// Limit, Offset и After игнорируются.
+func (r *SubscriptionRepo) Export(ctx context.Context, f domain.ListFilter, fn func(domain.Subscription) error) error {
+	f.After = nil
+
+	// копируем под блокировкой, чтобы fn (запись в сеть) не держала мьютекс
+	r.mu.RLock()
+	matched := make([]domain.Subscription, 0, len(r.items))
+	for _, s := range r.items {
+		if matchList(s, f) {
+			matched = append(matched, clone(s))
+		}
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(matched, f.Sort.Compare)
+	for _, s := range matched {
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SubscriptionRepo) Count(ctx context.Context, f domain.ListFilter) (int64, error) {
+	f.After = nil
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var n int64
+	for _, s := range r.items {
+		if matchList(s, f) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *SubscriptionRepo) Duplicates(ctx context.Context, f domain.DuplicateFilter) ([]domain.SubscriptionOverlap, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var subs []domain.Subscription
+	for _, s := range r.items {
+		if s.DeletedAt != nil || !matchServiceName(s, f.ServiceName) {
+			continue
+		}
+		if f.UserID != nil && *f.UserID != "" && s.UserID != *f.UserID {
+			continue
+		}
+		subs = append(subs, s)
+	}
+	slices.SortFunc(subs, func(a, b domain.Subscription) int { return cmp.Compare(a.ID, b.ID) })
+
+	var out []domain.SubscriptionOverlap
+	for i, a := range subs {
+		for _, b := range subs[i+1:] {
+			if a.Duplicates(b) {
+				out = append(out, domain.NewSubscriptionOverlap(a, b))
+			}
+		}
+	}
+	// как ORDER BY в postgres.SubscriptionRepo.Duplicates
+	slices.SortStableFunc(out, func(a, b domain.SubscriptionOverlap) int {
+		return cmp.Or(cmp.Compare(a.UserID, b.UserID), cmp.Compare(a.ServiceName, b.ServiceName))
+	})
+	return out, nil
+}
+
+func (r *SubscriptionRepo) Overlapping(ctx context.Context, s domain.Subscription) ([]domain.Subscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.overlapping(s), nil
+}
+
+// checkOverlaps — проверка строгого режима, под той же r.mu, что и запись.
+func (r *SubscriptionRepo) checkOverlaps(s domain.Subscription) error {
+	if others := r.overlapping(s); len(others) > 0 {
+		return service.OverlapConflict(s, others[0])
+	}
+	return nil
+}
+
+// overlapping вызывается под r.mu.
+func (r *SubscriptionRepo) overlapping(s domain.Subscription) []domain.Subscription {
+	var out []domain.Subscription
+	for _, other := range r.items {
+		if other.DeletedAt == nil && s.Duplicates(other) {
+			out = append(out, clone(other))
+		}
+	}
+	slices.SortFunc(out, func(a, b domain.Subscription) int {
+		return cmp.Or(a.StartDate.Compare(b.StartDate), cmp.Compare(a.ID, b.ID))
+	})
+	return out
+}
+
+func (r *SubscriptionRepo) TotalCost(ctx context.Context, f domain.TotalFilter) (domain.CostTotal, error) {
+	if err := f.Validate(); err != nil {
+		return domain.CostTotal{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	charges, err := r.charges(f)
+	if err != nil {
+		return domain.CostTotal{}, err
+	}
+
+	var gross, discount float64
+	for _, c := range charges {
+		gross += c.gross
+		discount += c.discount
+	}
+	total := domain.CostTotal{Gross: int64(math.Round(gross)), Discount: int64(math.Round(discount))}
+	total.Net = total.Gross - total.Discount
+	return total, nil
+}
+
+func (r *SubscriptionRepo) TotalBreakdown(ctx context.Context, f domain.TotalFilter) ([]domain.MonthlyCost, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	charges, err := r.charges(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []domain.MonthlyCost
+	index := make(map[time.Time]int)
+	for m := domain.MonthStartUTC(f.From); m.Before(f.ToExclusive()); m = domain.NextMonthStartUTC(m) {
+		index[m] = len(items)
+		items = append(items, domain.MonthlyCost{Month: m})
+	}
+
+	amounts := make([]float64, len(items))
+	seen := make(map[time.Time]map[int64]bool)
+	for _, c := range charges {
+		i := index[c.month]
+		amounts[i] += c.amount
+		if seen[c.month] == nil {
+			seen[c.month] = make(map[int64]bool)
+		}
+		if !seen[c.month][c.subscriptionID] {
+			seen[c.month][c.subscriptionID] = true
+			items[i].Subscriptions++
+		}
+	}
+	for i := range items {
+		items[i].Amount = int64(math.Round(amounts[i]))
+	}
+	return items, nil
+}
+
+func (r *SubscriptionRepo) TotalCostGrouped(ctx context.Context, f domain.TotalFilter) ([]domain.GroupTotal, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	if len(f.GroupBy) == 0 {
+		return nil, fmt.Errorf("group_by is empty")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	charges, err := r.charges(f)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		service, user, tag string
+		tagged             bool
+	}
+	type sums struct{ gross, discount float64 }
+	totals := make(map[key]sums)
+	add := func(k key, c charge) {
+		t := totals[k]
+		t.gross += c.gross
+		t.discount += c.discount
+		totals[k] = t
+	}
+	for _, c := range charges {
+		var k key
+		if f.Groups(domain.GroupByService) {
+			k.service = c.serviceName
+		}
+		if f.Groups(domain.GroupByUser) {
+			k.user = c.userID
+		}
+		if !f.Groups(domain.GroupByTag) {
+			add(k, c)
+			continue
+		}
+
+		// как LEFT JOIN subscription_tags: строка на каждый тег, без тегов — группа с tag = nil;
+		// при фильтре по тегам в группы попадают только теги из фильтра
+		tagged := false
+		for _, tag := range c.tags {
+			if len(f.Tags) > 0 && !slices.Contains(f.Tags, tag) {
+				continue
+			}
+			tk := k
+			tk.tag, tk.tagged = tag, true
+			add(tk, c)
+			tagged = true
+		}
+		if !tagged {
+			add(k, c)
+		}
+	}
+
+	items := make([]domain.GroupTotal, 0, len(totals))
+	for k, total := range totals {
+		g := domain.GroupTotal{CostTotal: domain.CostTotal{
+			Gross:    int64(math.Round(total.gross)),
+			Discount: int64(math.Round(total.discount)),
+		}}
+		g.Net = g.Gross - g.Discount
+		if f.Groups(domain.GroupByService) {
+			g.ServiceName = &k.service
+		}
+		if f.Groups(domain.GroupByUser) {
+			g.UserID = &k.user
+		}
+		if k.tagged {
+			g.Tag = &k.tag
+		}
+		items = append(items, g)
+	}
+	slices.SortFunc(items, func(a, b domain.GroupTotal) int {
+		return cmp.Or(
+			compareNullable(a.ServiceName, b.ServiceName),
+			compareNullable(a.UserID, b.UserID),
+			compareNullable(a.Tag, b.Tag),
+		)
+	})
+	return items, nil
+}
+
+// compareNullable сортирует nil после значений, как NULL в ORDER BY ... ASC.
+func compareNullable(a, b *string) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	default:
+		return strings.Compare(*a, *b)
+	}
+}
+
+// charge — аналог строки CTE charges из postgres.SubscriptionRepo.
+type charge struct {
+	month          time.Time
+	subscriptionID int64
+	serviceName    string
+	userID         string
+	tags           []string
+	gross          float64 // уже в f.TargetCurrency()
+	discount       float64
+	amount         float64 // gross - discount
+}
+
+// charges раскладывает подписки по месяцам периода. Вызывается под r.mu.
+func (r *SubscriptionRepo) charges(f domain.TotalFilter) ([]charge, error) {
+	target := f.TargetCurrency()
+
+	var out []charge
+	for m := domain.MonthStartUTC(f.From); m.Before(f.ToExclusive()); m = domain.NextMonthStartUTC(m) {
+		for _, s := range r.items {
+			if s.DeletedAt != nil || !matchServiceName(s, f.ServiceName) || !matchTags(s, f.Tags) {
+				continue
+			}
+			// та же логика пересечения, что и в JOIN по generate_series
+			if !s.ActiveIn(m) || s.InTrial(m) || s.PausedIn(m) {
+				continue
+			}
+			n := s.BillingPeriod.ChargesInMonth(s.StartDate, s.EndDate, m)
+			if n == 0 {
+				continue
+			}
+			price := domain.PriceAt(r.prices[s.ID], m, s.Price)
+			base := float64(price*n) * f.Proration.Factor(s, m)
+			discount := domain.DiscountAt(r.discounts[s.ID], m, base)
+
+			currency := s.Currency
+			if currency == "" {
+				currency = domain.BaseCurrency
+			}
+			rate, ok := r.rates.convert(m, currency, target, f.LatestRates)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s -> %s for %s",
+					service.ErrMissingExchangeRate, currency, target, domain.FormatMonthYear(m))
+			}
+
+			// как JOIN LATERAL shares: по строке на участника с его долей
+			for _, share := range s.Shares() {
+				if f.UserID != nil && *f.UserID != "" && share.UserID != *f.UserID {
+					continue
+				}
+				out = append(out, charge{
+					month:          m,
+					subscriptionID: s.ID,
+					serviceName:    s.ServiceName,
+					userID:         share.UserID,
+					tags:           s.Tags,
+					gross:          base * rate * share.Share,
+					discount:       discount * rate * share.Share,
+					amount:         (base - discount) * rate * share.Share,
+				})
+			}
+		}
+	}
+	return out, nil
+}
+
+// matchServiceName проверяет точное совпадение service_name; пустой фильтр подходит всем.
+func matchServiceName(s domain.Subscription, serviceName *string) bool {
+	return serviceName == nil || *serviceName == "" || s.ServiceName == *serviceName
+}
+
+// matchTags — есть ли у s хотя бы один тег из tags; пустой tags подходит всем.
+func matchTags(s domain.Subscription, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, t := range s.Tags {
+		if slices.Contains(tags, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchList(s domain.Subscription, f domain.ListFilter) bool {
+	if s.DeletedAt != nil && !f.IncludeDeleted {
+		return false
+	}
+	if len(f.UserIDs) > 0 && !slices.ContainsFunc(f.UserIDs, s.HasUser) {
+		return false
+	}
+	if !matchServiceName(s, f.ServiceName) || !matchTags(s, f.Tags) {
+		return false
+	}
+	if f.ServiceNamePrefix != nil && *f.ServiceNamePrefix != "" &&
+		!strings.HasPrefix(strings.ToLower(s.ServiceName), strings.ToLower(*f.ServiceNamePrefix)) {
+		return false
+	}
+	if f.PriceMin != nil && s.Price < *f.PriceMin {
+		return false
+	}
+	if f.PriceMax != nil && s.Price > *f.PriceMax {
+		return false
+	}
+	if f.Status != "" && !matchStatus(s, f.Status, domain.MonthStartUTC(f.StatusAt)) {
+		return false
+	}
+	if f.From != nil {
+		from := domain.MonthStartUTC(*f.From)
+		if s.EndDate != nil && s.EndDate.Before(from) {
+			return false
+		}
+	}
+	if f.To != nil {
+		toExclusive := domain.NextMonthStartUTC(*f.To)
+		if !s.StartDate.Before(toExclusive) {
+			return false
+		}
+	}
+	if (f.From != nil || f.To != nil) && !s.BilledBetween(f.From, f.To) {
+		return false
+	}
+	if f.After != nil && !afterCursor(s, f.Sort, *f.After) {
+		return false
+	}
+	return true
+}
+
+func matchStatus(s domain.Subscription, status domain.ListStatus, m time.Time) bool {
+	switch status {
+	case domain.StatusActive:
+		return s.ActiveIn(m) && !s.PausedIn(m)
+	case domain.StatusEnded:
+		return s.EndDate != nil && s.EndDate.Before(m)
+	case domain.StatusUpcoming:
+		return domain.MonthStartUTC(s.StartDate).After(m)
+	case domain.StatusTrial:
+		return s.ActiveIn(m) && s.InTrial(m) && !s.PausedIn(m)
+	case domain.StatusPaused:
+		return s.ActiveIn(m) && s.PausedIn(m)
+	default:
+		return true
+	}
+}
+
+// afterCursor — идёт ли s после строки курсора в порядке сортировки.
+func afterCursor(s domain.Subscription, order domain.ListSort, after domain.ListCursor) bool {
+	last := domain.Subscription{ID: after.ID}
+	switch key, _ := order.ParseKey(after.Key); v := key.(type) {
+	case int64:
+		last.Price = v
+	case time.Time:
+		last.StartDate = v
+	case string:
+		last.ServiceName = v
+	}
+	return order.Compare(s, last) > 0
+}
+
+// clone защищает хранимые данные от изменений через указатели вызывающей стороны.
+func clone(s domain.Subscription) domain.Subscription {
+	if s.ServiceID != nil {
+		id := *s.ServiceID
+		s.ServiceID = &id
+	}
+	if s.EndDate != nil {
+		end := *s.EndDate
+		s.EndDate = &end
+	}
+	if s.TrialEnd != nil {
+		trialEnd := *s.TrialEnd
+		s.TrialEnd = &trialEnd
+	}
+	if s.DeletedAt != nil {
+		deleted := *s.DeletedAt
+		s.DeletedAt = &deleted
+	}
+	s.Tags = slices.Clone(s.Tags)
+	s.Members = slices.Clone(s.Members)
+	s.Pauses = slices.Clone(s.Pauses)
+	for i, p := range s.Pauses {
+		if p.Until != nil {
+			until := *p.Until
+			s.Pauses[i].Until = &until
+		}
+	}
+	return s
+}
+
+// renameService переписывает service_name у подписок записи каталога serviceID
+// и пишет по событию update на каждую, как это делает postgres.CatalogRepo.Update.
+func (r *SubscriptionRepo) renameService(ctx context.Context, serviceID int64, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]int64, 0)
+	for id, s := range r.items {
+		if s.ServiceID != nil && *s.ServiceID == serviceID && s.ServiceName != name {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	now := time.Now().UTC()
+	for _, id := range ids {
+		before := r.items[id]
+		s := clone(before)
+		s.ServiceName = name
+		s.UpdatedAt = now
+		s.Version++
+		if err := r.recordEvent(ctx, id, domain.EventUpdate, &before, &s); err != nil {
+			return err
+		}
+		r.items[id] = s
+	}
+	return nil
+}
+
+// usesService сообщает, ссылается ли на запись каталога хоть одна подписка, включая удалённые.
+func (r *SubscriptionRepo) usesService(serviceID int64) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, s := range r.items {
+		if s.ServiceID != nil && *s.ServiceID == serviceID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"subscription_service/internal/domain"
+	"subscription_service/internal/repo/repotest"
+)
+
+const testUserID = "60610fee-2bf1-4721-ae6f-7636e79a0cba"
+
+func month(t *testing.T, s string) time.Time {
+	t.Helper()
+	m, err := domain.ParseMonthYear(s)
+	if err != nil {
+		t.Fatalf("parse %q: %v", s, err)
+	}
+	return m
+}
+
+func TestSubscriptionRepo(t *testing.T) {
+	repotest.SubscriptionRepo(t, func(t *testing.T) repotest.Storage {
+		rates := NewExchangeRateRepo()
+		return repotest.Storage{Subscriptions: NewSubscriptionRepo(rates), Rates: rates}
+	})
+}
@@ -0,0 +1,162 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"subscription_service/internal/domain"
+	"subscription_service/internal/service"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type BudgetRepo struct {
+	db *sqlx.DB
+}
+
+func NewBudgetRepo(db *sqlx.DB) *BudgetRepo {
+	return &BudgetRepo{db: db}
+}
+
+var _ service.BudgetRepository = (*BudgetRepo)(nil)
+
+const budgetColumns = `id, user_id::text AS user_id, amount, currency, tag, created_at, updated_at`
+
+const budgetAlertColumns = `id, budget_id, user_id::text AS user_id, month, limit_amount, spent, currency, created_at, notified_at`
+
+func (r *BudgetRepo) Create(ctx context.Context, b domain.Budget) (int64, error) {
+	var id int64
+	err := r.db.QueryRowxContext(ctx, `
+		INSERT INTO budgets (user_id, amount, currency, tag)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, b.UserID, b.Amount, b.Currency, b.Tag).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *BudgetRepo) GetByID(ctx context.Context, id int64) (*domain.Budget, error) {
+	var b domain.Budget
+	query := fmt.Sprintf(`SELECT %s FROM budgets WHERE id = $1`, budgetColumns)
+	if err := r.db.GetContext(ctx, &b, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (r *BudgetRepo) Update(ctx context.Context, b domain.Budget) (*domain.Budget, error) {
+	var updated domain.Budget
+	query := fmt.Sprintf(`
+		UPDATE budgets
+		SET amount = $1, currency = $2, tag = $3, updated_at = now()
+		WHERE id = $4
+		RETURNING %s
+	`, budgetColumns)
+	if err := r.db.GetContext(ctx, &updated, query, b.Amount, b.Currency, b.Tag, b.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (r *BudgetRepo) Delete(ctx context.Context, id int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM budgets WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *BudgetRepo) List(ctx context.Context, userID string) ([]domain.Budget, error) {
+	where := ""
+	args := make([]any, 0, 1)
+	if userID != "" {
+		args = append(args, userID)
+		where = fmt.Sprintf("WHERE user_id = $%d", len(args))
+	}
+
+	var items []domain.Budget
+	query := fmt.Sprintf(`SELECT %s FROM budgets %s ORDER BY id`, budgetColumns, where)
+	if err := r.db.SelectContext(ctx, &items, query, args...); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *BudgetRepo) AddAlert(ctx context.Context, o domain.BudgetOverrun) (*domain.BudgetAlert, error) {
+	var a domain.BudgetAlert
+	query := fmt.Sprintf(`
+		INSERT INTO budget_alerts (budget_id, user_id, month, limit_amount, spent, currency)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (budget_id, month) DO NOTHING
+		RETURNING %s
+	`, budgetAlertColumns)
+	err := r.db.GetContext(ctx, &a, query, o.BudgetID, o.UserID, o.Month, o.Limit, o.Spent, o.Currency)
+	if err != nil {
+		// ON CONFLICT DO NOTHING не возвращает строк
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *BudgetRepo) ListAlerts(ctx context.Context, f domain.BudgetAlertFilter) ([]domain.BudgetAlert, error) {
+	limit := f.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+
+	clauses := make([]string, 0, 2)
+	args := make([]any, 0, 4)
+	if f.UserID != "" {
+		args = append(args, f.UserID)
+		clauses = append(clauses, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if f.Pending {
+		clauses = append(clauses, "notified_at IS NULL")
+	}
+	where := ""
+	if len(clauses) > 0 {
+		where = "WHERE " + strings.Join(clauses, " AND ")
+	}
+
+	args = append(args, limit, f.Offset)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM budget_alerts
+		%s
+		ORDER BY id DESC
+		LIMIT $%d OFFSET $%d
+	`, budgetAlertColumns, where, len(args)-1, len(args))
+
+	var items []domain.BudgetAlert
+	if err := r.db.SelectContext(ctx, &items, query, args...); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *BudgetRepo) MarkNotified(ctx context.Context, alertID int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE budget_alerts SET notified_at = $1 WHERE id = $2`, at, alertID)
+	return err
+}
@@ -0,0 +1,366 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"subscription_service/internal/domain"
+	"subscription_service/internal/service"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type CatalogRepo struct {
+	db *sqlx.DB
+}
+
+func NewCatalogRepo(db *sqlx.DB) *CatalogRepo {
+	return &CatalogRepo{db: db}
+}
+
+var _ service.CatalogRepository = (*CatalogRepo)(nil)
+
+const serviceColumns = `id, name, category, default_price, created_at, updated_at`
+
+// catalogLockKey — ключ advisory-блокировки: имена и алиасы лежат в двух таблицах,
+// и уникальность между ними проверяется запросом, поэтому записи в каталог идут по одной.
+const catalogLockKey = 7_360_201
+
+func (r *CatalogRepo) Create(ctx context.Context, s domain.Service) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := lockCatalog(ctx, tx); err != nil {
+		return 0, err
+	}
+	if err := checkNamesFree(ctx, tx, s, 0); err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = tx.QueryRowxContext(ctx, `
+		INSERT INTO services (name, name_key, category, default_price)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, s.Name, domain.ServiceNameKey(s.Name), s.Category, s.DefaultPrice).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	if err := insertAliases(ctx, tx, id, s.Aliases); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *CatalogRepo) GetByID(ctx context.Context, id int64) (*domain.Service, error) {
+	return getService(ctx, r.db, "id = $1", id)
+}
+
+func (r *CatalogRepo) Update(ctx context.Context, s domain.Service) (*domain.Service, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := lockCatalog(ctx, tx); err != nil {
+		return nil, err
+	}
+	if err := checkNamesFree(ctx, tx, s, s.ID); err != nil {
+		return nil, err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE services
+		SET name = $1, name_key = $2, category = $3, default_price = $4, updated_at = now()
+		WHERE id = $5
+	`, s.Name, domain.ServiceNameKey(s.Name), s.Category, s.DefaultPrice, s.ID)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM service_aliases WHERE service_id = $1`, s.ID); err != nil {
+		return nil, err
+	}
+	if err := insertAliases(ctx, tx, s.ID, s.Aliases); err != nil {
+		return nil, err
+	}
+
+	// service_name в подписках — копия канонического имени, держим её в актуальном состоянии
+	if err := renameSubscriptions(ctx, tx, s.ID, s.Name); err != nil {
+		return nil, err
+	}
+
+	updated, err := getService(ctx, tx, "id = $1", s.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// renameSubscriptions переписывает service_name у подписок записи каталога serviceID
+// и пишет по событию update на каждую, как при PATCH.
+func renameSubscriptions(ctx context.Context, tx *sqlx.Tx, serviceID int64, name string) error {
+	var before []domain.Subscription
+	if err := tx.SelectContext(ctx, &before, `
+		SELECT `+subscriptionColumns+`
+		FROM subscriptions
+		WHERE service_id = $1 AND service_name <> $2
+		ORDER BY id
+		FOR UPDATE
+	`, serviceID, name); err != nil {
+		return err
+	}
+	if len(before) == 0 {
+		return nil
+	}
+	if err := loadDetails(ctx, tx, before); err != nil {
+		return err
+	}
+
+	ids := make([]int64, len(before))
+	for i, s := range before {
+		ids[i] = s.ID
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE subscriptions
+		SET service_name = $1, updated_at = now(), version = version + 1
+		WHERE id = ANY($2)
+	`, name, pq.Array(ids)); err != nil {
+		return err
+	}
+
+	var after []domain.Subscription
+	if err := tx.SelectContext(ctx, &after, `
+		SELECT `+subscriptionColumns+`
+		FROM subscriptions
+		WHERE id = ANY($1)
+		ORDER BY id
+	`, pq.Array(ids)); err != nil {
+		return err
+	}
+	if err := loadDetails(ctx, tx, after); err != nil {
+		return err
+	}
+
+	for i := range before {
+		if err := insertEvent(ctx, tx, before[i].ID, domain.EventUpdate, &before[i], &after[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *CatalogRepo) Delete(ctx context.Context, id int64) (bool, error) {
+	var used bool
+	if err := r.db.GetContext(ctx, &used, `SELECT EXISTS (SELECT 1 FROM subscriptions WHERE service_id = $1)`, id); err != nil {
+		return false, err
+	}
+	if used {
+		return false, service.ErrServiceInUse
+	}
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM services WHERE id = $1`, id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" { // foreign_key_violation
+			return false, service.ErrServiceInUse
+		}
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *CatalogRepo) List(ctx context.Context, f domain.ServiceFilter) ([]domain.Service, error) {
+	where := ""
+	args := make([]any, 0, 1)
+	if f.Category != nil && *f.Category != "" {
+		args = append(args, *f.Category)
+		where = fmt.Sprintf("WHERE lower(category) = lower($%d)", len(args))
+	}
+
+	var items []domain.Service
+	query := fmt.Sprintf(`SELECT %s FROM services %s ORDER BY name, id`, serviceColumns, where)
+	if err := r.db.SelectContext(ctx, &items, query, args...); err != nil {
+		return nil, err
+	}
+	if err := loadAliases(ctx, r.db, items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *CatalogRepo) Resolve(ctx context.Context, name string) (*domain.Service, error) {
+	return resolveService(ctx, r.db, name)
+}
+
+func (r *CatalogRepo) Ensure(ctx context.Context, name string) (*domain.Service, error) {
+	if s, err := resolveService(ctx, r.db, name); err != nil || s != nil {
+		return s, err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	s, err := ensureService(ctx, tx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ensureService находит запись по имени или заводит новую в транзакции tx.
+// Каталог остаётся заблокированным до конца tx.
+func ensureService(ctx context.Context, tx *sqlx.Tx, name string) (*domain.Service, error) {
+	if err := lockCatalog(ctx, tx); err != nil {
+		return nil, err
+	}
+	// пока ждали блокировку, имя могли завести параллельно
+	s, err := resolveService(ctx, tx, name)
+	if err != nil || s != nil {
+		return s, err
+	}
+
+	clean := domain.CleanServiceName(name)
+	var id int64
+	err = tx.QueryRowxContext(ctx, `
+		INSERT INTO services (name, name_key)
+		VALUES ($1, $2)
+		RETURNING id
+	`, clean, domain.ServiceNameKey(clean)).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return getService(ctx, tx, "id = $1", id)
+}
+
+func lockCatalog(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, catalogLockKey)
+	return err
+}
+
+// checkNamesFree проверяет, что имя и алиасы s не заняты другими записями (кроме selfID).
+func checkNamesFree(ctx context.Context, q sqlx.QueryerContext, s domain.Service, selfID int64) error {
+	keys := make([]string, 0, len(s.Aliases)+1)
+	keys = append(keys, domain.ServiceNameKey(s.Name))
+	for _, a := range s.Aliases {
+		keys = append(keys, domain.ServiceNameKey(a))
+	}
+
+	var taken bool
+	err := sqlx.GetContext(ctx, q, &taken, `
+		SELECT EXISTS (
+			SELECT 1 FROM services WHERE name_key = ANY($1) AND id <> $2
+			UNION ALL
+			SELECT 1 FROM service_aliases WHERE alias_key = ANY($1) AND service_id <> $2
+		)
+	`, pq.Array(keys), selfID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return service.ErrServiceNameTaken
+	}
+	return nil
+}
+
+func insertAliases(ctx context.Context, tx *sqlx.Tx, serviceID int64, aliases []string) error {
+	for _, a := range aliases {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO service_aliases (alias_key, alias, service_id)
+			VALUES ($1, $2, $3)
+		`, domain.ServiceNameKey(a), a, serviceID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func resolveService(ctx context.Context, q sqlx.QueryerContext, name string) (*domain.Service, error) {
+	key := domain.ServiceNameKey(name)
+	if key == "" {
+		return nil, nil
+	}
+	return getService(ctx, q, `
+		name_key = $1
+		OR id = (SELECT service_id FROM service_aliases WHERE alias_key = $1)
+	`, key)
+}
+
+// getService читает одну запись каталога с алиасами; where — условие с параметром $1.
+func getService(ctx context.Context, q sqlx.QueryerContext, where string, arg any) (*domain.Service, error) {
+	var s domain.Service
+	query := fmt.Sprintf(`SELECT %s FROM services WHERE %s LIMIT 1`, serviceColumns, where)
+	if err := sqlx.GetContext(ctx, q, &s, query, arg); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	items := []domain.Service{s}
+	if err := loadAliases(ctx, q, items); err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+func loadAliases(ctx context.Context, q sqlx.QueryerContext, items []domain.Service) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(items))
+	for _, s := range items {
+		ids = append(ids, s.ID)
+	}
+
+	var rows []struct {
+		ServiceID int64  `db:"service_id"`
+		Alias     string `db:"alias"`
+	}
+	err := sqlx.SelectContext(ctx, q, &rows, `
+		SELECT service_id, alias
+		FROM service_aliases
+		WHERE service_id = ANY($1)
+		ORDER BY alias
+	`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int64][]string, len(items))
+	for _, row := range rows {
+		byID[row.ServiceID] = append(byID[row.ServiceID], row.Alias)
+	}
+	for i := range items {
+		items[i].Aliases = byID[items[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"subscription_service/internal/domain"
+	"subscription_service/internal/service"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ExchangeRateRepo struct {
+	db *sqlx.DB
+}
+
+func NewExchangeRateRepo(db *sqlx.DB) *ExchangeRateRepo {
+	return &ExchangeRateRepo{db: db}
+}
+
+var _ service.ExchangeRateRepository = (*ExchangeRateRepo)(nil)
+
+func (r *ExchangeRateRepo) Upsert(ctx context.Context, rates []domain.ExchangeRate) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, rate := range rates {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO exchange_rates (month, currency, rate)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (month, currency)
+			DO UPDATE SET rate = EXCLUDED.rate, updated_at = now()
+		`, domain.MonthStartUTC(rate.Month), rate.Currency, rate.Rate)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *ExchangeRateRepo) List(ctx context.Context, f domain.ExchangeRateFilter) ([]domain.ExchangeRate, error) {
+	clauses := make([]string, 0, 3)
+	args := make([]any, 0, 3)
+
+	if f.Currency != nil && *f.Currency != "" {
+		args = append(args, *f.Currency)
+		clauses = append(clauses, fmt.Sprintf("currency = $%d", len(args)))
+	}
+	if f.From != nil {
+		args = append(args, domain.MonthStartUTC(*f.From))
+		clauses = append(clauses, fmt.Sprintf("month >= $%d", len(args)))
+	}
+	if f.To != nil {
+		args = append(args, domain.MonthStartUTC(*f.To))
+		clauses = append(clauses, fmt.Sprintf("month <= $%d", len(args)))
+	}
+
+	where := ""
+	if len(clauses) > 0 {
+		where = "WHERE " + strings.Join(clauses, " AND ")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT month, currency, rate
+		FROM exchange_rates
+		%s
+		ORDER BY month, currency
+	`, where)
+
+	var items []domain.ExchangeRate
+	if err := r.db.SelectContext(ctx, &items, query, args...); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
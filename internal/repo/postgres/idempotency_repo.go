@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"subscription_service/internal/domain"
+	"subscription_service/internal/service"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type IdempotencyRepo struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyRepo(db *sqlx.DB) *IdempotencyRepo {
+	return &IdempotencyRepo{db: db}
+}
+
+var _ service.IdempotencyRepository = (*IdempotencyRepo)(nil)
+
+// Reserve полагается на первичный ключ: из параллельных запросов с одним ключом
+// строку вставит (или перехватит истёкшую) только один, остальные прочитают её.
+func (r *IdempotencyRepo) Reserve(ctx context.Context, key, requestHash string, expiresAt time.Time) (*domain.IdempotencyRecord, bool, error) {
+	var reserved string
+	err := r.db.GetContext(ctx, &reserved, `
+		INSERT INTO idempotency_keys (key, request_hash, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash,
+		    status_code = NULL,
+		    response_headers = NULL,
+		    response = NULL,
+		    created_at = now(),
+		    expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= now()
+		RETURNING key
+	`, key, requestHash, expiresAt)
+	if err == nil {
+		return nil, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+
+	var row struct {
+		domain.IdempotencyRecord
+		RawHeaders []byte `db:"response_headers"`
+	}
+	err = r.db.GetContext(ctx, &row, `
+		SELECT key, request_hash, status_code, response_headers, response, expires_at
+		FROM idempotency_keys
+		WHERE key = $1
+	`, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	rec := row.IdempotencyRecord
+	if len(row.RawHeaders) > 0 {
+		if err := json.Unmarshal(row.RawHeaders, &rec.Headers); err != nil {
+			return nil, false, fmt.Errorf("decode response headers: %w", err)
+		}
+	}
+	return &rec, false, nil
+}
+
+// Complete обновляет только свою незавершённую запись: после истечения аренды ключ мог
+// перехватить запрос с другим отпечатком.
+func (r *IdempotencyRepo) Complete(ctx context.Context, rec domain.IdempotencyRecord) error {
+	// JSONB передаётся строкой: []byte lib/pq отправил бы как bytea
+	var headers *string
+	if len(rec.Headers) > 0 {
+		raw, err := json.Marshal(rec.Headers)
+		if err != nil {
+			return err
+		}
+		v := string(raw)
+		headers = &v
+	}
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = $3, response_headers = $4, response = $5, expires_at = $6
+		WHERE key = $1 AND request_hash = $2 AND status_code IS NULL
+	`, rec.Key, rec.RequestHash, rec.StatusCode, headers, rec.Response, rec.ExpiresAt)
+	return err
+}
+
+func (r *IdempotencyRepo) Release(ctx context.Context, key, requestHash string) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE key = $1 AND request_hash = $2 AND status_code IS NULL
+	`, key, requestHash)
+	return err
+}
+
+func (r *IdempotencyRepo) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
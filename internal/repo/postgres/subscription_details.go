@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+
+	"subscription_service/internal/domain"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// replaceTags заменяет набор тегов подписки; теги уже нормализованы сервисом.
+func replaceTags(ctx context.Context, tx *sqlx.Tx, subscriptionID int64, tags []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM subscription_tags WHERE subscription_id = $1`, subscriptionID); err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO subscription_tags (subscription_id, tag)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT DO NOTHING
+	`, subscriptionID, pq.Array(tags))
+	return err
+}
+
+// replaceMembers заменяет участников подписки; список уже проверен сервисом.
+func replaceMembers(ctx context.Context, tx *sqlx.Tx, subscriptionID int64, members []domain.SubscriptionMember) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM subscription_members WHERE subscription_id = $1`, subscriptionID); err != nil {
+		return err
+	}
+	for _, m := range members {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO subscription_members (subscription_id, user_id, weight)
+			VALUES ($1, $2, $3)
+		`, subscriptionID, m.UserID, m.Weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadDetails дочитывает теги, участников и паузы для items — по запросу на таблицу, а не на подписку.
+func loadDetails(ctx context.Context, q sqlx.QueryerContext, items []domain.Subscription) error {
+	if err := loadTags(ctx, q, items); err != nil {
+		return err
+	}
+	if err := loadMembers(ctx, q, items); err != nil {
+		return err
+	}
+	return loadPauses(ctx, q, items)
+}
+
+func loadTags(ctx context.Context, q sqlx.QueryerContext, items []domain.Subscription) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(items))
+	for _, s := range items {
+		ids = append(ids, s.ID)
+	}
+
+	var rows []struct {
+		SubscriptionID int64  `db:"subscription_id"`
+		Tag            string `db:"tag"`
+	}
+	err := sqlx.SelectContext(ctx, q, &rows, `
+		SELECT subscription_id, tag
+		FROM subscription_tags
+		WHERE subscription_id = ANY($1)
+		ORDER BY tag
+	`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int64][]string, len(items))
+	for _, row := range rows {
+		byID[row.SubscriptionID] = append(byID[row.SubscriptionID], row.Tag)
+	}
+	for i := range items {
+		items[i].Tags = byID[items[i].ID]
+	}
+	return nil
+}
+
+func loadMembers(ctx context.Context, q sqlx.QueryerContext, items []domain.Subscription) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(items))
+	for _, s := range items {
+		ids = append(ids, s.ID)
+	}
+
+	var rows []struct {
+		SubscriptionID int64  `db:"subscription_id"`
+		UserID         string `db:"user_id"`
+		Weight         int    `db:"weight"`
+	}
+	err := sqlx.SelectContext(ctx, q, &rows, `
+		SELECT subscription_id, user_id::text AS user_id, weight
+		FROM subscription_members
+		WHERE subscription_id = ANY($1)
+		ORDER BY user_id
+	`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int64][]domain.SubscriptionMember, len(items))
+	for _, row := range rows {
+		byID[row.SubscriptionID] = append(byID[row.SubscriptionID], domain.SubscriptionMember{UserID: row.UserID, Weight: row.Weight})
+	}
+	for i := range items {
+		items[i].Members = byID[items[i].ID]
+	}
+	return nil
+}
+
+func loadPauses(ctx context.Context, q sqlx.QueryerContext, items []domain.Subscription) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(items))
+	for _, s := range items {
+		ids = append(ids, s.ID)
+	}
+
+	var rows []struct {
+		SubscriptionID int64 `db:"subscription_id"`
+		domain.Pause
+	}
+	err := sqlx.SelectContext(ctx, q, &rows, `
+		SELECT subscription_id, paused_from, paused_until
+		FROM subscription_pauses
+		WHERE subscription_id = ANY($1)
+		ORDER BY paused_from
+	`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int64][]domain.Pause, len(items))
+	for _, row := range rows {
+		byID[row.SubscriptionID] = append(byID[row.SubscriptionID], row.Pause)
+	}
+	for i := range items {
+		items[i].Pauses = byID[items[i].ID]
+	}
+	return nil
+}
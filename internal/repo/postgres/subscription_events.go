@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+
+	"subscription_service/internal/domain"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// insertEvent пишет запись аудита в той же транзакции, что и само изменение.
+// request_id и actor берутся из контекста запроса.
+func insertEvent(ctx context.Context, tx *sqlx.Tx, subscriptionID int64, action domain.EventAction, before, after *domain.Subscription) error {
+	beforeJSON, err := domain.Snapshot(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := domain.Snapshot(after)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO subscription_events (subscription_id, action, before, after, request_id, actor)
+		VALUES ($1, $2, $3, $4, NULLIF($5::text, ''), NULLIF($6::text, ''))
+	`, subscriptionID, action, rawJSON(beforeJSON), rawJSON(afterJSON),
+		domain.RequestIDFromContext(ctx), domain.ActorFromContext(ctx))
+	return err
+}
+
+func (r *SubscriptionRepo) ListEvents(ctx context.Context, f domain.EventFilter) ([]domain.SubscriptionEvent, error) {
+	limit := f.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+
+	var items []domain.SubscriptionEvent
+	err := r.db.SelectContext(ctx, &items, `
+		SELECT id, subscription_id, action, before, after, request_id, actor, created_at
+		FROM subscription_events
+		WHERE subscription_id = $1
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3
+	`, f.SubscriptionID, limit, f.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// rawJSON превращает снимок в параметр для JSONB-колонки (nil -> NULL).
+// Передаём строкой: []byte lib/pq отправляет как bytea.
+func rawJSON(v *json.RawMessage) any {
+	if v == nil {
+		return nil
+	}
+	return string(*v)
+}
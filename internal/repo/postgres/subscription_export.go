@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"subscription_service/internal/domain"
+)
+
+// exportFetchSize — сколько строк читается из курсора за раз.
+const exportFetchSize = 500
+
+// Export проходит по всем подпискам под фильтром через серверный курсор,
+// поэтому в памяти одновременно держится не больше exportFetchSize строк.
+// Limit, Offset и After игнорируются.
+func (r *SubscriptionRepo) Export(ctx context.Context, f domain.ListFilter, fn func(domain.Subscription) error) error {
+	f.After = nil
+	where, args := buildWhereList(f)
+
+	// курсор живёт только внутри транзакции; снимок данных согласован на её начало
+	tx, err := r.db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	declare := fmt.Sprintf(`
+		DECLARE subscriptions_export NO SCROLL CURSOR FOR
+		SELECT %s
+		FROM subscriptions
+		%s
+		%s
+	`, subscriptionColumns, where, buildOrderBy(f.Sort))
+	if _, err := tx.ExecContext(ctx, declare, args...); err != nil {
+		return err
+	}
+
+	fetch := fmt.Sprintf("FETCH %d FROM subscriptions_export", exportFetchSize)
+	for {
+		var batch []domain.Subscription
+		if err := tx.SelectContext(ctx, &batch, fetch); err != nil {
+			return err
+		}
+		if err := loadDetails(ctx, tx, batch); err != nil {
+			return err
+		}
+		for _, s := range batch {
+			if err := fn(s); err != nil {
+				return err
+			}
+		}
+		if len(batch) < exportFetchSize {
+			break
+		}
+	}
+
+	return tx.Commit()
+}
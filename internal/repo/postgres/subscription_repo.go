@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"subscription_service/internal/service"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type SubscriptionRepo struct {
@@ -23,138 +25,892 @@ func NewSubscriptionRepo(db *sqlx.DB) *SubscriptionRepo {
 
 var _ service.SubscriptionRepository = (*SubscriptionRepo)(nil)
 
-func (r *SubscriptionRepo) Create(ctx context.Context, s domain.Subscription) (int64, error) {
+const subscriptionColumns = `id, service_id, service_name, price, currency, billing_period, user_id, start_date, end_date, trial_end, created_at, updated_at, deleted_at, version`
+
+func (r *SubscriptionRepo) Create(ctx context.Context, s domain.Subscription, rejectOverlaps bool) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if rejectOverlaps {
+		if err := checkOverlaps(ctx, tx, s); err != nil {
+			return 0, err
+		}
+	}
+	id, err := createSubscription(ctx, tx, s)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// CreateBatch вставляет все подписки в одной транзакции: либо все, либо ни одной.
+// Записи каталога для подписок без service_id заводятся в той же транзакции.
+func (r *SubscriptionRepo) CreateBatch(ctx context.Context, subs []domain.Subscription) ([]int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	subs = slices.Clone(subs)
+	for i := range subs {
+		if subs[i].ServiceID != nil {
+			continue
+		}
+		svc, err := ensureService(ctx, tx, subs[i].ServiceName)
+		if err != nil {
+			return nil, err
+		}
+		subs[i].ServiceID = &svc.ID
+		subs[i].ServiceName = svc.Name
+	}
+
+	ids := make([]int64, 0, len(subs))
+	for _, s := range subs {
+		id, err := createSubscription(ctx, tx, s)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// createSubscription вставляет подписку с начальной ценой и событием создания.
+func createSubscription(ctx context.Context, tx *sqlx.Tx, s domain.Subscription) (int64, error) {
 	var id int64
-	err := r.db.QueryRowxContext(ctx, `
-		INSERT INTO subscriptions (service_name, price, user_id, start_date, end_date)
-		VALUES ($1, $2, $3, $4, $5)
+	err := tx.QueryRowxContext(ctx, `
+		INSERT INTO subscriptions (service_name, price, currency, billing_period, user_id, start_date, end_date, service_id, trial_end)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id
-	`, s.ServiceName, s.Price, s.UserID, s.StartDate, s.EndDate).Scan(&id)
+	`, s.ServiceName, s.Price, s.Currency, s.BillingPeriod, s.UserID, s.StartDate, s.EndDate, s.ServiceID, s.TrialEnd).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	// начальная цена действует с первого месяца подписки
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO subscription_prices (subscription_id, effective_from, price)
+		VALUES ($1, $2, $3)
+	`, id, domain.MonthStartUTC(s.StartDate), s.Price); err != nil {
+		return 0, err
+	}
+	if err := replaceTags(ctx, tx, id, s.Tags); err != nil {
+		return 0, err
+	}
+	if err := replaceMembers(ctx, tx, id, s.Members); err != nil {
+		return 0, err
+	}
+
+	created, err := getSubscription(ctx, tx, id, false)
 	if err != nil {
 		return 0, err
 	}
+	if err := insertEvent(ctx, tx, id, domain.EventCreate, nil, created); err != nil {
+		return 0, err
+	}
 	return id, nil
 }
 
 func (r *SubscriptionRepo) GetByID(ctx context.Context, id int64) (*domain.Subscription, error) {
-	var s domain.Subscription
-	err := r.db.GetContext(ctx, &s, `
-		SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at
-		FROM subscriptions
-		WHERE id = $1
-	`, id)
+	return getSubscription(ctx, r.db, id, false)
+}
+
+func (r *SubscriptionRepo) Update(ctx context.Context, s domain.Subscription, price *domain.PriceChange, rejectOverlaps bool) (*domain.Subscription, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if rejectOverlaps {
+		if err := checkOverlaps(ctx, tx, s); err != nil {
+			return nil, err
 		}
+	}
+	before, err := getSubscription(ctx, tx, s.ID, true)
+	if err != nil {
 		return nil, err
 	}
-	return &s, nil
-}
+	if before == nil {
+		return nil, nil
+	}
 
-func (r *SubscriptionRepo) Update(ctx context.Context, s domain.Subscription) (*domain.Subscription, error) {
-	_, err := r.db.ExecContext(ctx, `
+	res, err := tx.ExecContext(ctx, `
 		UPDATE subscriptions
 		SET service_name = $1,
 		    price = $2,
-		    user_id = $3,
-		    start_date = $4,
-		    end_date = $5,
-		    updated_at = now()
-		WHERE id = $6
-	`, s.ServiceName, s.Price, s.UserID, s.StartDate, s.EndDate, s.ID)
+		    currency = $3,
+		    billing_period = $4,
+		    user_id = $5,
+		    start_date = $6,
+		    end_date = $7,
+		    service_id = $10,
+		    trial_end = $11,
+		    updated_at = now(),
+		    version = version + 1
+		WHERE id = $8 AND version = $9
+	`, s.ServiceName, s.Price, s.Currency, s.BillingPeriod, s.UserID, s.StartDate, s.EndDate, s.ID, s.Version, s.ServiceID, s.TrialEnd)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, service.ErrPreconditionFailed
+	}
+
+	if price != nil {
+		// новая цена перекрывает все изменения, запланированные с этого месяца и позже
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM subscription_prices
+			WHERE subscription_id = $1 AND effective_from >= $2
+		`, s.ID, domain.MonthStartUTC(price.EffectiveFrom)); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO subscription_prices (subscription_id, effective_from, price)
+			VALUES ($1, $2, $3)
+		`, s.ID, domain.MonthStartUTC(price.EffectiveFrom), price.Price); err != nil {
+			return nil, err
+		}
+	}
+
+	// если start_date сдвинули раньше, первая цена должна покрывать и новые месяцы
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE subscription_prices
+		SET effective_from = $2
+		WHERE subscription_id = $1
+		  AND effective_from > $2
+		  AND effective_from = (SELECT MIN(effective_from) FROM subscription_prices WHERE subscription_id = $1)
+	`, s.ID, domain.MonthStartUTC(s.StartDate)); err != nil {
+		return nil, err
+	}
+
+	if err := replaceTags(ctx, tx, s.ID, s.Tags); err != nil {
+		return nil, err
+	}
+	if err := replaceMembers(ctx, tx, s.ID, s.Members); err != nil {
+		return nil, err
+	}
+
+	after, err := getSubscription(ctx, tx, s.ID, false)
 	if err != nil {
 		return nil, err
 	}
-	return r.GetByID(ctx, s.ID)
+	if err := insertEvent(ctx, tx, s.ID, domain.EventUpdate, before, after); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return after, nil
 }
 
-func (r *SubscriptionRepo) Delete(ctx context.Context, id int64) (bool, error) {
-	res, err := r.db.ExecContext(ctx, `DELETE FROM subscriptions WHERE id = $1`, id)
+func (r *SubscriptionRepo) ListPrices(ctx context.Context, id int64) ([]domain.PriceChange, error) {
+	var items []domain.PriceChange
+	err := r.db.SelectContext(ctx, &items, `
+		SELECT subscription_id, effective_from, price
+		FROM subscription_prices
+		WHERE subscription_id = $1
+		ORDER BY effective_from
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *SubscriptionRepo) AddDiscount(ctx context.Context, d domain.Discount) (domain.Discount, error) {
+	err := r.db.QueryRowxContext(ctx, `
+		INSERT INTO subscription_discounts (subscription_id, kind, value, code, from_month, until_month)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, d.SubscriptionID, d.Kind, d.Value, d.Code, d.From, d.Until).Scan(&d.ID, &d.CreatedAt)
+	if err != nil {
+		return domain.Discount{}, err
+	}
+	return d, nil
+}
+
+func (r *SubscriptionRepo) ListDiscounts(ctx context.Context, id int64) ([]domain.Discount, error) {
+	var items []domain.Discount
+	err := r.db.SelectContext(ctx, &items, `
+		SELECT id, subscription_id, kind, value, code, from_month, until_month, created_at
+		FROM subscription_discounts
+		WHERE subscription_id = $1
+		ORDER BY from_month, id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *SubscriptionRepo) DeleteDiscount(ctx context.Context, id, discountID int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM subscription_discounts WHERE id = $1 AND subscription_id = $2
+	`, discountID, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// Delete помечает подписку удалённой; строка физически удаляется позже через Purge.
+func (r *SubscriptionRepo) Delete(ctx context.Context, id int64, version int64) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return false, err
 	}
+	defer func() { _ = tx.Rollback() }()
 
+	before, err := getSubscription(ctx, tx, id, true)
+	if err != nil {
+		return false, err
+	}
+	if before == nil {
+		return false, nil
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE subscriptions
+		SET deleted_at = now(), updated_at = now(), version = version + 1
+		WHERE id = $1 AND ($2 = 0 OR version = $2)
+	`, id, version)
+	if err != nil {
+		return false, err
+	}
 	affected, err := res.RowsAffected()
 	if err != nil {
 		return false, err
 	}
+	if affected == 0 {
+		return false, service.ErrPreconditionFailed
+	}
+	if err := insertEvent(ctx, tx, id, domain.EventDelete, before, nil); err != nil {
+		return false, err
+	}
 
-	return affected > 0, nil
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *SubscriptionRepo) Restore(ctx context.Context, id int64) (*domain.Subscription, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE subscriptions
+		SET deleted_at = NULL, updated_at = now(), version = version + 1
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, nil
+	}
+
+	after, err := getSubscription(ctx, tx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := insertEvent(ctx, tx, id, domain.EventRestore, nil, after); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+func (r *SubscriptionRepo) Pause(ctx context.Context, id int64, p domain.Pause) (*domain.Subscription, error) {
+	return r.changePauses(ctx, id, domain.EventPause, func(tx *sqlx.Tx, before *domain.Subscription) error {
+		for _, existing := range before.Pauses {
+			if existing.Overlaps(p) {
+				return service.ErrAlreadyPaused
+			}
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO subscription_pauses (subscription_id, paused_from, paused_until)
+			VALUES ($1, $2, $3)
+		`, id, p.From, p.Until)
+		return err
+	})
+}
+
+func (r *SubscriptionRepo) Resume(ctx context.Context, id int64, at time.Time) (*domain.Subscription, error) {
+	return r.changePauses(ctx, id, domain.EventResume, func(tx *sqlx.Tx, before *domain.Subscription) error {
+		i := before.PauseAt(at)
+		if i < 0 {
+			return service.ErrNotPaused
+		}
+		current := before.Pauses[i]
+		resumed, ok := current.ResumedAt(at)
+		if !ok {
+			_, err := tx.ExecContext(ctx, `
+				DELETE FROM subscription_pauses WHERE subscription_id = $1 AND paused_from = $2
+			`, id, current.From)
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `
+			UPDATE subscription_pauses SET paused_until = $3
+			WHERE subscription_id = $1 AND paused_from = $2
+		`, id, current.From, resumed.Until)
+		return err
+	})
+}
+
+// changePauses блокирует подписку, применяет change к её паузам, увеличивает версию
+// и пишет событие action в той же транзакции. nil, если подписки нет.
+func (r *SubscriptionRepo) changePauses(ctx context.Context, id int64, action domain.EventAction, change func(tx *sqlx.Tx, before *domain.Subscription) error) (*domain.Subscription, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	before, err := getSubscription(ctx, tx, id, true)
+	if err != nil || before == nil {
+		return nil, err
+	}
+	if err := change(tx, before); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE subscriptions SET updated_at = now(), version = version + 1 WHERE id = $1
+	`, id); err != nil {
+		return nil, err
+	}
+
+	after, err := getSubscription(ctx, tx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := insertEvent(ctx, tx, id, action, before, after); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+// Purge физически удаляет подписки, помеченные удалёнными раньше olderThan.
+// История цен удаляется каскадно, журнал аудита остаётся.
+func (r *SubscriptionRepo) Purge(ctx context.Context, olderThan time.Time) (int64, error) {
+	var purged int64
+	err := r.db.GetContext(ctx, &purged, `
+		WITH purged AS (
+			DELETE FROM subscriptions
+			WHERE deleted_at IS NOT NULL AND deleted_at < $1
+			RETURNING id
+		), logged AS (
+			INSERT INTO subscription_events (subscription_id, action, request_id, actor)
+			SELECT id, $2::text, NULLIF($3::text, ''), NULLIF($4::text, '')
+			FROM purged
+			RETURNING 1
+		)
+		SELECT COUNT(*) FROM logged
+	`, olderThan, domain.EventPurge, domain.RequestIDFromContext(ctx), domain.ActorFromContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// getSubscription читает неудалённую подписку через db или tx; forUpdate блокирует строку до конца транзакции.
+func getSubscription(ctx context.Context, q sqlx.QueryerContext, id int64, forUpdate bool) (*domain.Subscription, error) {
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM subscriptions
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	var s domain.Subscription
+	if err := sqlx.GetContext(ctx, q, &s, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	items := []domain.Subscription{s}
+	if err := loadDetails(ctx, q, items); err != nil {
+		return nil, err
+	}
+	return &items[0], nil
 }
 
 func (r *SubscriptionRepo) List(ctx context.Context, f domain.ListFilter) ([]domain.Subscription, error) {
 	where, args := buildWhereList(f)
 
 	limit := f.Limit
-	if limit <= 0 || limit > 200 {
-		limit = 50
+	if limit <= 0 {
+		limit = domain.DefaultListLimit
 	}
-	if f.Offset < 0 {
-		f.Offset = 0
+	offset := f.Offset
+	if offset < 0 || f.After != nil {
+		offset = 0
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at
+		SELECT %s
 		FROM subscriptions
 		%s
-		ORDER BY id
+		%s
 		LIMIT %d OFFSET %d
-	`, where, limit, f.Offset)
+	`, subscriptionColumns, where, buildOrderBy(f.Sort), limit, offset)
 
 	var items []domain.Subscription
 	if err := r.db.SelectContext(ctx, &items, query, args...); err != nil {
 		return nil, err
 	}
+	if err := loadDetails(ctx, r.db, items); err != nil {
+		return nil, err
+	}
 	return items, nil
 }
 
-func (r *SubscriptionRepo) TotalCost(ctx context.Context, f domain.TotalFilter) (int64, error) {
-	if err := f.Validate(); err != nil {
+func (r *SubscriptionRepo) Count(ctx context.Context, f domain.ListFilter) (int64, error) {
+	f.After = nil
+	where, args := buildWhereList(f)
+
+	var n int64
+	if err := r.db.GetContext(ctx, &n, "SELECT count(*) FROM subscriptions "+where, args...); err != nil {
 		return 0, err
 	}
+	return n, nil
+}
+
+// Duplicates сводит подписки сами с собой: пересечение дат как в domain.Subscription.Overlaps,
+// общий период — от поздней даты начала до ранней даты окончания (LEAST пропускает NULL).
+func (r *SubscriptionRepo) Duplicates(ctx context.Context, f domain.DuplicateFilter) ([]domain.SubscriptionOverlap, error) {
+	clauses := []string{"a.deleted_at IS NULL"}
+	args := make([]any, 0, 2)
+	if f.UserID != nil && *f.UserID != "" {
+		args = append(args, *f.UserID)
+		clauses = append(clauses, fmt.Sprintf("a.user_id = $%d", len(args)))
+	}
+	if f.ServiceName != nil && *f.ServiceName != "" {
+		args = append(args, *f.ServiceName)
+		clauses = append(clauses, fmt.Sprintf("a.service_name = $%d", len(args)))
+	}
+
+	query := `
+		SELECT a.user_id::text AS user_id,
+		       a.service_name,
+		       a.id AS first_id,
+		       b.id AS second_id,
+		       GREATEST(a.start_date, b.start_date) AS from_date,
+		       LEAST(a.end_date, b.end_date) AS to_date
+		FROM subscriptions a
+		JOIN subscriptions b
+		  ON b.user_id = a.user_id
+		 AND b.service_name = a.service_name
+		 AND b.id > a.id
+		 AND b.deleted_at IS NULL
+		 AND b.start_date <= COALESCE(a.end_date, 'infinity'::date)
+		 AND a.start_date <= COALESCE(b.end_date, 'infinity'::date)
+		WHERE ` + strings.Join(clauses, " AND ") + `
+		ORDER BY a.user_id, a.service_name, a.id, b.id
+	`
+
+	var items []domain.SubscriptionOverlap
+	if err := r.db.SelectContext(ctx, &items, query, args...); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *SubscriptionRepo) Overlapping(ctx context.Context, s domain.Subscription) ([]domain.Subscription, error) {
+	return overlapping(ctx, r.db, s)
+}
+
+// overlapLockKey — первый ключ advisory-блокировки строгого режима, второй — хэш пары user_id и service_name.
+const overlapLockKey = 7_360_202
+
+// checkOverlaps — проверка строгого режима в транзакции записи. Блокировка по user_id и service_name
+// держится до конца tx, поэтому два строгих запроса не могут одновременно пройти проверку
+// и записать пересекающиеся подписки.
+func checkOverlaps(ctx context.Context, tx *sqlx.Tx, s domain.Subscription) error {
+	if _, err := tx.ExecContext(ctx, `
+		SELECT pg_advisory_xact_lock($1, hashtext($2::text || '/' || $3::text))
+	`, overlapLockKey, s.UserID, s.ServiceName); err != nil {
+		return err
+	}
+	others, err := overlapping(ctx, tx, s)
+	if err != nil {
+		return err
+	}
+	if len(others) > 0 {
+		return service.OverlapConflict(s, others[0])
+	}
+	return nil
+}
+
+func overlapping(ctx context.Context, q sqlx.QueryerContext, s domain.Subscription) ([]domain.Subscription, error) {
+	var items []domain.Subscription
+	err := sqlx.SelectContext(ctx, q, &items, `
+		SELECT `+subscriptionColumns+`
+		FROM subscriptions
+		WHERE deleted_at IS NULL
+		  AND user_id = $1
+		  AND service_name = $2
+		  AND id <> $3
+		  AND start_date <= COALESCE($4::date, 'infinity'::date)
+		  AND COALESCE(end_date, 'infinity'::date) >= $5
+		ORDER BY start_date, id
+	`, s.UserID, s.ServiceName, s.ID, s.EndDate, s.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadDetails(ctx, q, items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *SubscriptionRepo) TotalCost(ctx context.Context, f domain.TotalFilter) (domain.CostTotal, error) {
+	if err := f.Validate(); err != nil {
+		return domain.CostTotal{}, err
+	}
+
+	cte, args := buildChargesCTE(f)
+	if err := r.checkExchangeRates(ctx, f, cte, args); err != nil {
+		return domain.CostTotal{}, err
+	}
+
+	// net считается из округлённых gross и discount, чтобы в ответе сходилась арифметика
+	query := cte + `
+		SELECT t.gross, t.discount, t.gross - t.discount AS net
+		FROM (
+			SELECT COALESCE(ROUND(SUM(gross)), 0)::bigint AS gross,
+			       COALESCE(ROUND(SUM(discount)), 0)::bigint AS discount
+			FROM charges
+		) t
+	`
+
+	var total domain.CostTotal
+	if err := r.db.GetContext(ctx, &total, query, args...); err != nil {
+		return domain.CostTotal{}, err
+	}
+	return total, nil
+}
+
+func (r *SubscriptionRepo) TotalBreakdown(ctx context.Context, f domain.TotalFilter) ([]domain.MonthlyCost, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	cte, args := buildChargesCTE(f)
+	if err := r.checkExchangeRates(ctx, f, cte, args); err != nil {
+		return nil, err
+	}
+
+	query := cte + `
+		SELECT months.m AS month,
+		       COALESCE(ROUND(SUM(c.amount)), 0)::bigint AS amount,
+		       COUNT(DISTINCT c.subscription_id) AS subscriptions
+		FROM months
+		LEFT JOIN charges c ON c.month = months.m
+		GROUP BY months.m
+		ORDER BY months.m
+	`
+
+	var items []domain.MonthlyCost
+	if err := r.db.SelectContext(ctx, &items, query, args...); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *SubscriptionRepo) TotalCostGrouped(ctx context.Context, f domain.TotalFilter) ([]domain.GroupTotal, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	cte, args := buildChargesCTE(f)
+	if err := r.checkExchangeRates(ctx, f, cte, args); err != nil {
+		return nil, err
+	}
+
+	// набор колонок берётся только из фиксированного списка, пользовательский ввод сюда не попадает
+	columns := make([]string, 0, 3)
+	if f.Groups(domain.GroupByService) {
+		columns = append(columns, "c.service_name")
+	}
+	if f.Groups(domain.GroupByUser) {
+		columns = append(columns, "c.user_id::text AS user_id")
+	}
+	join := ""
+	if f.Groups(domain.GroupByTag) {
+		// подписка попадает в группу каждого своего тега; без тегов — в группу tag = NULL.
+		// При фильтре по тегам группы строятся только по тегам из фильтра.
+		columns = append(columns, "t.tag")
+		join = "LEFT JOIN subscription_tags t ON t.subscription_id = c.subscription_id"
+		if len(f.Tags) > 0 {
+			args = append(args, pq.Array(f.Tags))
+			join += fmt.Sprintf(" AND t.tag = ANY($%d::text[])", len(args))
+		}
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("group_by is empty")
+	}
+
+	groupBy := make([]string, len(columns))
+	for i := range columns {
+		groupBy[i] = fmt.Sprintf("%d", i+1)
+	}
 
+	query := cte + fmt.Sprintf(`
+		SELECT g.*, g.gross - g.discount AS net
+		FROM (
+			SELECT %s,
+			       COALESCE(ROUND(SUM(c.gross)), 0)::bigint AS gross,
+			       COALESCE(ROUND(SUM(c.discount)), 0)::bigint AS discount
+			FROM charges c
+			%s
+			GROUP BY %s
+		) g
+		ORDER BY %s
+	`, strings.Join(columns, ", "), join, strings.Join(groupBy, ", "), strings.Join(groupBy, ", "))
+
+	var items []domain.GroupTotal
+	if err := r.db.SelectContext(ctx, &items, query, args...); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// checkExchangeRates ищет списания, которые нельзя перевести в целевую валюту из-за отсутствия курса.
+func (r *SubscriptionRepo) checkExchangeRates(ctx context.Context, f domain.TotalFilter, cte string, args []any) error {
+	var missing []struct {
+		Month    time.Time `db:"month"`
+		Currency string    `db:"currency"`
+	}
+	query := cte + `
+		SELECT month, currency
+		FROM charges
+		WHERE amount IS NULL
+		ORDER BY month, currency
+		LIMIT 1
+	`
+	if err := r.db.SelectContext(ctx, &missing, query, args...); err != nil {
+		return err
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s -> %s for %s",
+		service.ErrMissingExchangeRate, missing[0].Currency, f.TargetCurrency(), domain.FormatMonthYear(missing[0].Month))
+}
+
+// buildChargesCTE строит CTE months (все месяцы периода) и charges
+// (по строке на каждую пару месяц/подписка, за которую в этом месяце списываются деньги).
+// Все расчёты стоимости собираются поверх charges.
+//
+// Число списаний в месяце зависит от billing_period и должно совпадать с domain.BillingPeriod.ChargesInMonth:
+// monthly — каждый месяц, quarterly/yearly — каждый 3-й/12-й месяц от start_date,
+// weekly — количество дат start_date + 7k, попавших в месяц не позже end_date.
+//
+// Подписка попадает в месяц, если действует хотя бы один его день. С proration=daily
+// месячное списание умножается на долю дней месяца, которую покрывает подписка (см. prorationSQL).
+//
+// Месяцы пробного периода (до trial_end включительно) бесплатны и в charges не попадают.
+//
+// Цена берётся из subscription_prices — последняя, вступившая в силу не позже месяца списания.
+//
+// Общая подписка даёт по строке на участника: user_id — участник, amount — его доля (см. memberSharesSQL).
+//
+// gross — стоимость без скидок, discount — сумма скидок из subscription_discounts, действующих в этом месяце
+// (как domain.DiscountAt), amount = gross - discount.
+//
+// amount в charges уже переведён в f.TargetCurrency() по курсам exchange_rates за месяц списания
+// (с f.LatestRates — по последним курсам не позже этого месяца).
+// Если нужного курса нет, amount = NULL (см. checkExchangeRates).
+func buildChargesCTE(f domain.TotalFilter) (string, []any) {
 	toExclusive := f.ToExclusive()
 
-	where, args := buildWhereBase(f.UserID, f.ServiceName, "s")
+	where, args := buildWhereCharges(f)
 
 	args = append(args, f.From, time.Date(toExclusive.Year(), toExclusive.Month()-1, 1, 0, 0, 0, 0, time.UTC))
+	fromArg, toArg := len(args)-1, len(args)
+
+	args = append(args, f.TargetCurrency(), domain.BaseCurrency)
+	targetArg, baseArg := len(args)-1, len(args)
+
+	// курс за месяц списания, а для прогноза — последний известный на этот месяц
+	rateMonthOp := "="
+	if f.LatestRates {
+		rateMonthOp = "<="
+	}
 
 	query := fmt.Sprintf(`
 		WITH months AS (
 			SELECT generate_series($%d::date, $%d::date, interval '1 month')::date AS m
+		),
+		charges AS (
+			SELECT billed.month,
+			       billed.subscription_id,
+			       billed.service_name,
+			       billed.user_id,
+			       billed.currency,
+			       billed.gross * fx.rate AS gross,
+			       billed.discount * fx.rate AS discount,
+			       (billed.gross - billed.discount) * fx.rate AS amount
+			FROM (
+				SELECT months.m AS month,
+				       s.id AS subscription_id,
+				       s.service_name,
+				       sh.user_id,
+				       s.currency,
+				       b.base * sh.share AS gross,
+				       LEAST(b.base, b.base * dc.percent / 100 + dc.fixed) * sh.share AS discount
+				FROM months
+				JOIN subscriptions s
+				  ON s.start_date < months.m + interval '1 month'
+				 AND (s.end_date IS NULL OR s.end_date >= months.m)
+				 AND (s.trial_end IS NULL OR s.trial_end < months.m)
+				 AND NOT `+pausedClause("s", "months.m")+`
+				 AND s.deleted_at IS NULL
+				LEFT JOIN LATERAL (
+					SELECT p.price
+					FROM subscription_prices p
+					WHERE p.subscription_id = s.id
+					  AND p.effective_from <= months.m
+					ORDER BY p.effective_from DESC
+					LIMIT 1
+				) sp ON true
+				CROSS JOIN LATERAL (SELECT COALESCE(sp.price, s.price) * (%[5]s) * (%[8]s) AS base) b
+				CROSS JOIN LATERAL (
+					SELECT COALESCE(SUM(d.value) FILTER (WHERE d.kind = 'percent'), 0) AS percent,
+					       COALESCE(SUM(d.value) FILTER (WHERE d.kind = 'fixed'), 0) AS fixed
+					FROM subscription_discounts d
+					WHERE d.subscription_id = s.id
+					  AND d.from_month <= months.m
+					  AND (d.until_month IS NULL OR d.until_month >= months.m)
+				) dc
+				JOIN LATERAL (%[7]s) sh ON true
+				%[6]s
+			) billed
+			LEFT JOIN LATERAL (
+				SELECT e.rate FROM exchange_rates e
+				WHERE e.currency = billed.currency AND e.month %[9]s billed.month
+				ORDER BY e.month DESC
+				LIMIT 1
+			) src ON true
+			LEFT JOIN LATERAL (
+				SELECT e.rate FROM exchange_rates e
+				WHERE e.currency = $%[3]d::text AND e.month %[9]s billed.month
+				ORDER BY e.month DESC
+				LIMIT 1
+			) dst ON true
+			CROSS JOIN LATERAL (
+				SELECT CASE
+				    WHEN billed.currency = $%[3]d::text THEN 1
+				    ELSE (CASE WHEN billed.currency = $%[4]d::text THEN 1 ELSE src.rate END)
+				       / (CASE WHEN $%[3]d::text = $%[4]d::text THEN 1 ELSE dst.rate END)
+				END AS rate
+			) fx
+			WHERE billed.gross > 0
 		)
-		SELECT COALESCE(SUM(s.price), 0) AS total
-		FROM months
-		JOIN subscriptions s
-		  ON s.start_date <= months.m
-		 AND (s.end_date IS NULL OR s.end_date >= months.m)
-		%s
-	`, len(args)-1, len(args), where)
+	`, fromArg, toArg, targetArg, baseArg, billingChargesSQL, where, memberSharesSQL, prorationSQL(f.Proration), rateMonthOp)
 
-	var total int64
-	if err := r.db.GetContext(ctx, &total, query, args...); err != nil {
-		return 0, err
-	}
-	return total, nil
+	return query, args
 }
 
-func buildWhereBase(userID, serviceName *string, alias string) (string, []any) {
-	clauses := make([]string, 0, 2)
-	args := make([]any, 0, 2)
+// memberSharesSQL — доли подписки s по участникам, как domain.Subscription.Shares:
+// вес участника к сумме весов, а без участников вся стоимость у s.user_id.
+const memberSharesSQL = `
+	SELECT m.user_id, m.weight::numeric / SUM(m.weight) OVER () AS share
+	FROM subscription_members m
+	WHERE m.subscription_id = s.id
+	UNION ALL
+	SELECT s.user_id, 1
+	WHERE NOT EXISTS (SELECT 1 FROM subscription_members m WHERE m.subscription_id = s.id)`
 
-	prefix := ""
-	if alias != "" {
-		prefix = alias + "."
+// billingChargesSQL — число списаний подписки s в месяце months.m.
+const billingChargesSQL = `
+	CASE s.billing_period
+		WHEN 'weekly' THEN
+			GREATEST(0, ((LEAST((months.m + interval '1 month')::date, s.end_date + 1) - s.start_date) + 6) / 7)
+			- GREATEST(0, ((months.m - s.start_date) + 6) / 7)
+		WHEN 'quarterly' THEN
+			CASE WHEN MOD(` + monthsSinceStartSQL + `, 3) = 0 THEN 1 ELSE 0 END
+		WHEN 'yearly' THEN
+			CASE WHEN MOD(` + monthsSinceStartSQL + `, 12) = 0 THEN 1 ELSE 0 END
+		ELSE 1
+	END`
+
+// prorationSQL — множитель списания подписки s в месяце months.m, как domain.Proration.Factor.
+func prorationSQL(p domain.Proration) string {
+	if p != domain.ProrationDaily {
+		return "1"
 	}
+	// LEAST пропускает NULL, так что бессрочная подписка покрывает месяц до последнего дня
+	return `CASE s.billing_period
+		WHEN 'weekly' THEN 1
+		WHEN 'quarterly' THEN 1
+		WHEN 'yearly' THEN 1
+		ELSE (LEAST(s.end_date, (months.m + interval '1 month' - interval '1 day')::date)
+		      - GREATEST(s.start_date, months.m) + 1)::numeric
+		     / ((months.m + interval '1 month')::date - months.m)
+	END`
+}
+
+const monthsSinceStartSQL = `((EXTRACT(YEAR FROM months.m) - EXTRACT(YEAR FROM s.start_date)) * 12
+	+ EXTRACT(MONTH FROM months.m) - EXTRACT(MONTH FROM s.start_date))::int`
 
-	if userID != nil && *userID != "" {
-		args = append(args, *userID)
-		clauses = append(clauses, fmt.Sprintf("%suser_id = $%d", prefix, len(args)))
+// buildWhereCharges — условия для подписки s и доли участника sh в buildChargesCTE.
+// Пользователь фильтруется по доле, поэтому участнику общей подписки достаётся только его часть.
+func buildWhereCharges(f domain.TotalFilter) (string, []any) {
+	clauses := make([]string, 0, 3)
+	args := make([]any, 0, 3)
+
+	if f.UserID != nil && *f.UserID != "" {
+		args = append(args, *f.UserID)
+		clauses = append(clauses, fmt.Sprintf("sh.user_id = $%d", len(args)))
 	}
-	if serviceName != nil && *serviceName != "" {
-		args = append(args, *serviceName)
-		clauses = append(clauses, fmt.Sprintf("%sservice_name = $%d", prefix, len(args)))
+	if f.ServiceName != nil && *f.ServiceName != "" {
+		args = append(args, *f.ServiceName)
+		clauses = append(clauses, fmt.Sprintf("s.service_name = $%d", len(args)))
+	}
+	if len(f.Tags) > 0 {
+		clauses = append(clauses, tagsClause("s", f.Tags, &args))
 	}
 
 	if len(clauses) == 0 {
@@ -164,17 +920,27 @@ func buildWhereBase(userID, serviceName *string, alias string) (string, []any) {
 }
 
 func buildWhereList(f domain.ListFilter) (string, []any) {
-	clauses := make([]string, 0, 4)
-	args := make([]any, 0, 4)
+	clauses := make([]string, 0, 8)
+	args := make([]any, 0, 8)
 
-	if f.UserID != nil && *f.UserID != "" {
-		args = append(args, *f.UserID)
-		clauses = append(clauses, fmt.Sprintf("user_id = $%d", len(args)))
+	if len(f.UserIDs) > 0 {
+		// плательщик или участник общей подписки
+		args = append(args, pq.Array(f.UserIDs))
+		clauses = append(clauses, fmt.Sprintf(`(user_id = ANY($%[1]d::uuid[]) OR EXISTS (
+			SELECT 1 FROM subscription_members sm
+			WHERE sm.subscription_id = subscriptions.id AND sm.user_id = ANY($%[1]d::uuid[])))`, len(args)))
 	}
 	if f.ServiceName != nil && *f.ServiceName != "" {
 		args = append(args, *f.ServiceName)
 		clauses = append(clauses, fmt.Sprintf("service_name = $%d", len(args)))
 	}
+	if f.ServiceNamePrefix != nil && *f.ServiceNamePrefix != "" {
+		args = append(args, likeEscaper.Replace(*f.ServiceNamePrefix)+"%")
+		clauses = append(clauses, fmt.Sprintf("service_name ILIKE $%d", len(args)))
+	}
+	if len(f.Tags) > 0 {
+		clauses = append(clauses, tagsClause("subscriptions", f.Tags, &args))
+	}
 
 	if f.From != nil {
 		from := domain.MonthStartUTC(*f.From)
@@ -186,9 +952,129 @@ func buildWhereList(f domain.ListFilter) (string, []any) {
 		args = append(args, toExclusive)
 		clauses = append(clauses, fmt.Sprintf("start_date < $%d", len(args)))
 	}
+	if f.From != nil || f.To != nil {
+		clauses = append(clauses, billedBetweenClause(f.From, f.To, &args))
+	}
+
+	if f.PriceMin != nil {
+		args = append(args, *f.PriceMin)
+		clauses = append(clauses, fmt.Sprintf("price >= $%d", len(args)))
+	}
+	if f.PriceMax != nil {
+		args = append(args, *f.PriceMax)
+		clauses = append(clauses, fmt.Sprintf("price <= $%d", len(args)))
+	}
+
+	if f.Status != "" {
+		args = append(args, domain.MonthStartUTC(f.StatusAt), domain.NextMonthStartUTC(f.StatusAt))
+		n, next := len(args)-1, len(args)
+		switch f.Status {
+		case domain.StatusActive:
+			clauses = append(clauses, fmt.Sprintf("start_date < $%d AND (end_date IS NULL OR end_date >= $%d)", next, n),
+				"NOT "+pausedClause("subscriptions", fmt.Sprintf("$%d", n)))
+		case domain.StatusEnded:
+			clauses = append(clauses, fmt.Sprintf("end_date < $%d", n))
+		case domain.StatusUpcoming:
+			clauses = append(clauses, fmt.Sprintf("start_date >= $%d", next))
+		case domain.StatusTrial:
+			clauses = append(clauses, fmt.Sprintf("start_date < $%d AND trial_end >= $%d", next, n),
+				"NOT "+pausedClause("subscriptions", fmt.Sprintf("$%d", n)))
+		case domain.StatusPaused:
+			clauses = append(clauses, fmt.Sprintf("start_date < $%d AND (end_date IS NULL OR end_date >= $%d)", next, n),
+				pausedClause("subscriptions", fmt.Sprintf("$%d", n)))
+		}
+	}
+
+	if !f.IncludeDeleted {
+		clauses = append(clauses, "deleted_at IS NULL")
+	}
+
+	if f.After != nil {
+		clauses = append(clauses, keysetClause(f.Sort, *f.After, &args))
+	}
 
 	if len(clauses) == 0 {
 		return "", args
 	}
 	return "WHERE " + strings.Join(clauses, " AND "), args
 }
+
+// pausedClause — подписка из table приостановлена в месяце month (SQL-выражение с датой).
+func pausedClause(table, month string) string {
+	return fmt.Sprintf(`EXISTS (
+		SELECT 1 FROM subscription_pauses p
+		WHERE p.subscription_id = %[1]s.id
+		  AND p.paused_from <= %[2]s AND (p.paused_until IS NULL OR p.paused_until >= %[2]s))`, table, month)
+}
+
+// billedBetweenClause — у подписки есть неприостановленный месяц в [from, to], как domain.Subscription.BilledBetween.
+// Первый такой месяц — либо начало пересечения периодов, либо месяц сразу после одной из пауз.
+func billedBetweenClause(from, to *time.Time, args *[]any) string {
+	var lo, hi any
+	if from != nil {
+		lo = domain.MonthStartUTC(*from)
+	}
+	if to != nil {
+		hi = domain.MonthStartUTC(*to)
+	}
+	*args = append(*args, lo, hi)
+	// GREATEST и LEAST пропускают NULL, поэтому открытые границы работают без отдельных веток
+	return fmt.Sprintf(`EXISTS (
+		SELECT 1 FROM (
+			SELECT GREATEST(date_trunc('month', subscriptions.start_date)::date, $%[1]d::date) AS m
+			UNION ALL
+			SELECT (pp.paused_until + interval '1 month')::date
+			FROM subscription_pauses pp
+			WHERE pp.subscription_id = subscriptions.id AND pp.paused_until IS NOT NULL
+		) c
+		WHERE c.m >= GREATEST(date_trunc('month', subscriptions.start_date)::date, $%[1]d::date)
+		  AND (LEAST(subscriptions.end_date, $%[2]d::date) IS NULL OR c.m <= LEAST(subscriptions.end_date, $%[2]d::date))
+		  AND NOT %[3]s)`, len(*args)-1, len(*args), pausedClause("subscriptions", "c.m"))
+}
+
+// tagsClause — у подписки из table есть хотя бы один тег из tags.
+func tagsClause(table string, tags []string, args *[]any) string {
+	*args = append(*args, pq.Array(tags))
+	return fmt.Sprintf(
+		"EXISTS (SELECT 1 FROM subscription_tags st WHERE st.subscription_id = %s.id AND st.tag = ANY($%d::text[]))",
+		table, len(*args))
+}
+
+// listSortColumns — белый список колонок для ORDER BY: значение из запроса в SQL не попадает.
+var listSortColumns = map[domain.SortField]string{
+	domain.SortByID:          "id",
+	domain.SortByPrice:       "price",
+	domain.SortByStartDate:   "start_date",
+	domain.SortByServiceName: "service_name",
+}
+
+// likeEscaper экранирует спецсимволы LIKE, чтобы префикс искался буквально.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func buildOrderBy(sort domain.ListSort) string {
+	dir := "ASC"
+	if sort.Desc {
+		dir = "DESC"
+	}
+	col := listSortColumns[sort.Field]
+	if col == "" || col == "id" {
+		return "ORDER BY id " + dir
+	}
+	return fmt.Sprintf("ORDER BY %s %s, id %s", col, dir, dir)
+}
+
+// keysetClause выбирает строки строго после курсора в порядке buildOrderBy.
+func keysetClause(sort domain.ListSort, after domain.ListCursor, args *[]any) string {
+	op := ">"
+	if sort.Desc {
+		op = "<"
+	}
+	col := listSortColumns[sort.Field]
+	key, _ := sort.ParseKey(after.Key) // курсор проверен в сервисе
+	if col == "" || col == "id" || key == nil {
+		*args = append(*args, after.ID)
+		return fmt.Sprintf("id %s $%d", op, len(*args))
+	}
+	*args = append(*args, key, after.ID)
+	return fmt.Sprintf("(%s, id) %s ($%d, $%d)", col, op, len(*args)-1, len(*args))
+}
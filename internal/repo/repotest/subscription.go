@@ -0,0 +1,814 @@
+// Package repotest — общие проверки хранилищ: одни и те же случаи
+// прогоняются для memory и postgres, чтобы их семантика не расходилась.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"subscription_service/internal/domain"
+	"subscription_service/internal/service"
+)
+
+const testUserID = "60610fee-2bf1-4721-ae6f-7636e79a0cba"
+
+// Storage — хранилище под проверкой. Rates должен быть источником курсов для Subscriptions.
+type Storage struct {
+	Subscriptions service.SubscriptionRepository
+	Rates         service.ExchangeRateRepository
+}
+
+// SubscriptionRepo прогоняет проверки service.SubscriptionRepository.
+// newStorage вызывается для каждого случая и должен возвращать пустое хранилище,
+// в котором id подписок начинаются с 1.
+func SubscriptionRepo(t *testing.T, newStorage func(t *testing.T) Storage) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T, st Storage)
+	}{
+		{"CreateGetUpdateDelete", testCreateGetUpdateDelete},
+		{"List_MonthOverlap", testList_MonthOverlap},
+		{"TotalCost_SumsByMonth", testTotalCost_SumsByMonth},
+		{"TotalBreakdown_OneRowPerMonth", testTotalBreakdown_OneRowPerMonth},
+		{"TotalCostGrouped_ByService", testTotalCostGrouped_ByService},
+		{"TotalCostGrouped_ByTag", testTotalCostGrouped_ByTag},
+		{"TotalCost_SharedSubscriptionSplitsByWeight", testTotalCost_SharedSubscriptionSplitsByWeight},
+		{"TotalBreakdown_TrialMonthsAreFree", testTotalBreakdown_TrialMonthsAreFree},
+		{"PauseResume_PausedMonthsAreSkipped", testPauseResume_PausedMonthsAreSkipped},
+		{"TotalBreakdown_DailyProration", testTotalBreakdown_DailyProration},
+		{"TotalCost_AppliesDiscountsPerMonth", testTotalCost_AppliesDiscountsPerMonth},
+		{"TotalBreakdown_BillingPeriods", testTotalBreakdown_BillingPeriods},
+		{"TotalCost_ConvertsWithMonthlyRates", testTotalCost_ConvertsWithMonthlyRates},
+		{"TotalBreakdown_LatestRates", testTotalBreakdown_LatestRates},
+		{"TotalCost_UsesPriceValidForEachMonth", testTotalCost_UsesPriceValidForEachMonth},
+		{"Events_RecordedForEachChange", testEvents_RecordedForEachChange},
+		{"SoftDelete_RestoreAndPurge", testSoftDelete_RestoreAndPurge},
+		{"Version_StaleUpdateAndDelete", testVersion_StaleUpdateAndDelete},
+		{"List_KeysetAndCount", testList_KeysetAndCount},
+		{"List_SortAndFilters", testList_SortAndFilters},
+		{"Export_IgnoresPagination", testExport_IgnoresPagination},
+		{"StrictMode_RejectsOverlapsOnWrite", testStrictMode_RejectsOverlapsOnWrite},
+		{"Duplicates_OverlappingRangesOfSameService", testDuplicates_OverlappingRangesOfSameService},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newStorage(t)
+			s.Subscriptions = withDefaults{s.Subscriptions}
+			c.run(t, s)
+		})
+	}
+}
+
+// withDefaults заполняет валюту и период оплаты, как это делает сервис перед сохранением:
+// postgres не примет пустые значения, а случаи ниже их не указывают.
+type withDefaults struct {
+	service.SubscriptionRepository
+}
+
+func (r withDefaults) Create(ctx context.Context, s domain.Subscription, rejectOverlaps bool) (int64, error) {
+	if s.Currency == "" {
+		s.Currency = domain.BaseCurrency
+	}
+	if s.BillingPeriod == "" {
+		s.BillingPeriod = domain.BillingMonthly
+	}
+	return r.SubscriptionRepository.Create(ctx, s, rejectOverlaps)
+}
+
+func month(t *testing.T, s string) time.Time {
+	t.Helper()
+	m, err := domain.ParseMonthYear(s)
+	if err != nil {
+		t.Fatalf("parse %q: %v", s, err)
+	}
+	return m
+}
+
+func testCreateGetUpdateDelete(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+
+	id, err := repo.Create(ctx, domain.Subscription{
+		ServiceName: "Netflix",
+		Price:       400,
+		UserID:      testUserID,
+		StartDate:   month(t, "07-2025"),
+	}, false)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	got, err := repo.GetByID(ctx, id)
+	if err != nil || got == nil {
+		t.Fatalf("expected subscription, got %v, %v", got, err)
+	}
+
+	got.Price = 500
+	updated, err := repo.Update(ctx, *got, nil, false)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if updated.Price != 500 {
+		t.Fatalf("expected price 500, got %d", updated.Price)
+	}
+
+	deleted, err := repo.Delete(ctx, id, 0)
+	if err != nil || !deleted {
+		t.Fatalf("expected deleted, got %v, %v", deleted, err)
+	}
+
+	missing, err := repo.GetByID(ctx, id)
+	if err != nil || missing != nil {
+		t.Fatalf("expected nil, nil; got %v, %v", missing, err)
+	}
+}
+
+func testList_MonthOverlap(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+
+	end := month(t, "03-2025")
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Old", Price: 100, UserID: testUserID, StartDate: month(t, "01-2025"), EndDate: &end}, false)
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Open", Price: 100, UserID: testUserID, StartDate: month(t, "02-2025")}, false)
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Future", Price: 100, UserID: testUserID, StartDate: month(t, "09-2025")}, false)
+
+	from := month(t, "04-2025")
+	to := month(t, "08-2025")
+	items, err := repo.List(ctx, domain.ListFilter{From: &from, To: &to})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if len(items) != 1 || items[0].ServiceName != "Open" {
+		t.Fatalf("expected only 'Open', got %+v", items)
+	}
+}
+
+func testTotalCost_SumsByMonth(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+
+	end := month(t, "08-2025")
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Netflix", Price: 400, UserID: testUserID, StartDate: month(t, "07-2025"), EndDate: &end}, false)
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Spotify", Price: 200, UserID: testUserID, StartDate: month(t, "08-2025")}, false)
+
+	total, err := repo.TotalCost(ctx, domain.TotalFilter{
+		From: month(t, "06-2025"),
+		To:   month(t, "10-2025"),
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	// Netflix: 07, 08 -> 800; Spotify: 08, 09, 10 -> 600
+	if total.Net != 1400 {
+		t.Fatalf("expected 1400, got %d", total.Net)
+	}
+
+	service := "Spotify"
+	total, err = repo.TotalCost(ctx, domain.TotalFilter{
+		ServiceName: &service,
+		From:        month(t, "06-2025"),
+		To:          month(t, "10-2025"),
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if total.Net != 600 {
+		t.Fatalf("expected 600, got %d", total.Net)
+	}
+}
+
+func testTotalBreakdown_OneRowPerMonth(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Netflix", Price: 400, UserID: testUserID, StartDate: month(t, "07-2025")}, false)
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Spotify", Price: 200, UserID: testUserID, StartDate: month(t, "08-2025")}, false)
+
+	items, err := repo.TotalBreakdown(ctx, domain.TotalFilter{
+		From: month(t, "06-2025"),
+		To:   month(t, "08-2025"),
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	want := []domain.MonthlyCost{
+		{Month: month(t, "06-2025"), Amount: 0, Subscriptions: 0},
+		{Month: month(t, "07-2025"), Amount: 400, Subscriptions: 1},
+		{Month: month(t, "08-2025"), Amount: 600, Subscriptions: 2},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("expected %d rows, got %d", len(want), len(items))
+	}
+	for i := range want {
+		// месяц сравнивается через Equal: драйвер может вернуть другую *time.Location
+		if !items[i].Month.Equal(want[i].Month) || items[i].Amount != want[i].Amount || items[i].Subscriptions != want[i].Subscriptions {
+			t.Fatalf("row %d: expected %+v, got %+v", i, want[i], items[i])
+		}
+	}
+}
+
+func testTotalCostGrouped_ByService(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Netflix", Price: 400, UserID: testUserID, StartDate: month(t, "07-2025")}, false)
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Netflix", Price: 300, UserID: "1b4e28ba-2fa1-11d2-883f-0016d3cca427", StartDate: month(t, "08-2025")}, false)
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Spotify", Price: 200, UserID: testUserID, StartDate: month(t, "08-2025")}, false)
+
+	items, err := repo.TotalCostGrouped(ctx, domain.TotalFilter{
+		From:    month(t, "07-2025"),
+		To:      month(t, "08-2025"),
+		GroupBy: []domain.GroupByField{domain.GroupByService},
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 groups, got %+v", items)
+	}
+	if *items[0].ServiceName != "Netflix" || items[0].Net != 1100 || items[0].UserID != nil {
+		t.Fatalf("unexpected Netflix group: %+v", items[0])
+	}
+	if *items[1].ServiceName != "Spotify" || items[1].Net != 200 {
+		t.Fatalf("unexpected Spotify group: %+v", items[1])
+	}
+}
+
+func testTotalCostGrouped_ByTag(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "GitHub", Price: 400, UserID: testUserID, StartDate: month(t, "07-2025"), Tags: []string{"cloud", "dev"}}, false)
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Netflix", Price: 300, UserID: testUserID, StartDate: month(t, "07-2025"), Tags: []string{"entertainment"}}, false)
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Gym", Price: 200, UserID: testUserID, StartDate: month(t, "07-2025")}, false)
+
+	f := domain.TotalFilter{
+		From:    month(t, "07-2025"),
+		To:      month(t, "07-2025"),
+		GroupBy: []domain.GroupByField{domain.GroupByTag},
+	}
+	items, err := repo.TotalCostGrouped(ctx, f)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	// подписка с двумя тегами попадает в обе группы, без тегов — в группу tag = nil в конце
+	if len(items) != 4 || *items[0].Tag != "cloud" || *items[1].Tag != "dev" || items[1].Net != 400 ||
+		*items[2].Tag != "entertainment" || items[3].Tag != nil || items[3].Net != 200 {
+		t.Fatalf("unexpected groups: %+v", items)
+	}
+
+	f.Tags = []string{"dev", "entertainment"}
+	items, err = repo.TotalCostGrouped(ctx, f)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if len(items) != 2 || *items[0].Tag != "dev" || *items[1].Tag != "entertainment" {
+		t.Fatalf("expected only filtered tags, got %+v", items)
+	}
+
+	total, err := repo.TotalCost(ctx, f)
+	if err != nil || total.Net != 700 {
+		t.Fatalf("expected 700, got %d, %v", total.Net, err)
+	}
+}
+
+func testTotalCost_SharedSubscriptionSplitsByWeight(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+	member := "1b4e28ba-2fa1-11d2-883f-0016d3cca427"
+
+	_, _ = repo.Create(ctx, domain.Subscription{
+		ServiceName: "Family Plan",
+		Price:       900,
+		UserID:      testUserID,
+		StartDate:   month(t, "07-2025"),
+		Members:     []domain.SubscriptionMember{{UserID: testUserID, Weight: 2}, {UserID: member, Weight: 1}},
+	}, false)
+
+	f := domain.TotalFilter{From: month(t, "07-2025"), To: month(t, "08-2025")}
+	if total, err := repo.TotalCost(ctx, f); err != nil || total.Net != 1800 {
+		t.Fatalf("expected 1800 without user filter, got %d, %v", total.Net, err)
+	}
+	f.UserID = &member
+	if total, err := repo.TotalCost(ctx, f); err != nil || total.Net != 600 {
+		t.Fatalf("expected member share 600, got %d, %v", total.Net, err)
+	}
+
+	items, err := repo.List(ctx, domain.ListFilter{UserIDs: []string{member}})
+	if err != nil || len(items) != 1 {
+		t.Fatalf("expected shared subscription in member's list, got %+v, %v", items, err)
+	}
+}
+
+func testTotalBreakdown_TrialMonthsAreFree(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+	trialEnd := month(t, "08-2025")
+
+	_, _ = repo.Create(ctx, domain.Subscription{
+		ServiceName: "Netflix",
+		Price:       400,
+		UserID:      testUserID,
+		StartDate:   month(t, "07-2025"),
+		TrialEnd:    &trialEnd,
+	}, false)
+
+	items, err := repo.TotalBreakdown(ctx, domain.TotalFilter{From: month(t, "07-2025"), To: month(t, "09-2025")})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if len(items) != 3 || items[0].Amount != 0 || items[1].Amount != 0 || items[1].Subscriptions != 0 || items[2].Amount != 400 {
+		t.Fatalf("expected charges only after trial, got %+v", items)
+	}
+
+	for at, want := range map[string]int{"08-2025": 1, "09-2025": 0} {
+		got, err := repo.List(ctx, domain.ListFilter{Status: domain.StatusTrial, StatusAt: month(t, at)})
+		if err != nil || len(got) != want {
+			t.Fatalf("status=trial at %s: expected %d, got %d, %v", at, want, len(got), err)
+		}
+	}
+}
+
+func testPauseResume_PausedMonthsAreSkipped(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+
+	id, _ := repo.Create(ctx, domain.Subscription{ServiceName: "Netflix", Price: 400, UserID: testUserID, StartDate: month(t, "01-2025")}, false)
+	if _, err := repo.Pause(ctx, id, domain.Pause{From: month(t, "03-2025")}); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if _, err := repo.Pause(ctx, id, domain.Pause{From: month(t, "06-2025")}); !errors.Is(err, service.ErrAlreadyPaused) {
+		t.Fatalf("expected ErrAlreadyPaused, got %v", err)
+	}
+	resumed, err := repo.Resume(ctx, id, month(t, "05-2025"))
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if len(resumed.Pauses) != 1 || !resumed.Pauses[0].Until.Equal(month(t, "04-2025")) || resumed.Version != 3 {
+		t.Fatalf("expected pause 03-2025..04-2025 at version 3, got %+v", resumed)
+	}
+	if _, err := repo.Resume(ctx, id, month(t, "05-2025")); !errors.Is(err, service.ErrNotPaused) {
+		t.Fatalf("expected ErrNotPaused, got %v", err)
+	}
+
+	total, err := repo.TotalCost(ctx, domain.TotalFilter{From: month(t, "01-2025"), To: month(t, "06-2025")})
+	if err != nil || total.Net != 1600 {
+		t.Fatalf("expected 4 paid months = 1600, got %d, %v", total.Net, err)
+	}
+
+	from, to := month(t, "03-2025"), month(t, "04-2025")
+	if got, _ := repo.List(ctx, domain.ListFilter{From: &from, To: &to}); len(got) != 0 {
+		t.Fatalf("expected no overlap with paused months, got %d", len(got))
+	}
+	to = month(t, "05-2025")
+	if got, _ := repo.List(ctx, domain.ListFilter{From: &from, To: &to}); len(got) != 1 {
+		t.Fatalf("expected overlap in 05-2025, got %d", len(got))
+	}
+	if got, _ := repo.List(ctx, domain.ListFilter{Status: domain.StatusPaused, StatusAt: from}); len(got) != 1 {
+		t.Fatalf("status=paused: expected 1, got %d", len(got))
+	}
+}
+
+func testTotalBreakdown_DailyProration(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+	end := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	_, _ = repo.Create(ctx, domain.Subscription{
+		ServiceName: "Netflix",
+		Price:       310,
+		UserID:      testUserID,
+		StartDate:   time.Date(2025, 1, 22, 0, 0, 0, 0, time.UTC),
+		EndDate:     &end,
+	}, false)
+
+	f := domain.TotalFilter{From: month(t, "01-2025"), To: month(t, "04-2025")}
+	full, err := repo.TotalBreakdown(ctx, f)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if full[0].Amount != 310 || full[2].Amount != 310 || full[3].Amount != 0 {
+		t.Fatalf("without proration partial months are charged in full, got %+v", full)
+	}
+
+	f.Proration = domain.ProrationDaily
+	prorated, err := repo.TotalBreakdown(ctx, f)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	// январь: 22..31 — 10 дней из 31, март: 1..10 — 10 из 31
+	if prorated[0].Amount != 100 || prorated[1].Amount != 310 || prorated[2].Amount != 100 || prorated[3].Amount != 0 {
+		t.Fatalf("expected 100, 310, 100, 0, got %+v", prorated)
+	}
+}
+
+func testTotalCost_AppliesDiscountsPerMonth(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+
+	id, _ := repo.Create(ctx, domain.Subscription{ServiceName: "Netflix", Price: 400, UserID: testUserID, StartDate: month(t, "01-2025")}, false)
+	until := month(t, "04-2025")
+	_, _ = repo.AddDiscount(ctx, domain.Discount{SubscriptionID: id, Kind: domain.DiscountPercent, Value: 50, From: month(t, "02-2025"), Until: &until})
+	fixed, _ := repo.AddDiscount(ctx, domain.Discount{SubscriptionID: id, Kind: domain.DiscountFixed, Value: 300, From: month(t, "04-2025")})
+
+	f := domain.TotalFilter{From: month(t, "01-2025"), To: month(t, "05-2025")}
+	// 01: 400, 02-03: 200, 04: 50% + 300 больше цены — 0, 05: 400 - 300 = 100
+	total, err := repo.TotalCost(ctx, f)
+	if err != nil || total != (domain.CostTotal{Gross: 2000, Discount: 1100, Net: 900}) {
+		t.Fatalf("expected 2000 - 1100 = 900, got %+v, %v", total, err)
+	}
+	items, _ := repo.TotalBreakdown(ctx, f)
+	if items[3].Amount != 0 || items[4].Amount != 100 {
+		t.Fatalf("breakdown must be after discounts, got %+v", items)
+	}
+
+	if ok, _ := repo.DeleteDiscount(ctx, id, fixed.ID); !ok {
+		t.Fatalf("expected discount %d to be deleted", fixed.ID)
+	}
+	if total, _ := repo.TotalCost(ctx, f); total.Net != 1400 {
+		t.Fatalf("expected 1400 without fixed discount, got %+v", total)
+	}
+}
+
+func testTotalBreakdown_BillingPeriods(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Gym", Price: 10, BillingPeriod: domain.BillingWeekly, UserID: testUserID, StartDate: month(t, "01-2025")}, false)
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Cloud", Price: 300, BillingPeriod: domain.BillingQuarterly, UserID: testUserID, StartDate: month(t, "01-2025")}, false)
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "IDE", Price: 1200, BillingPeriod: domain.BillingYearly, UserID: testUserID, StartDate: month(t, "01-2025")}, false)
+
+	items, err := repo.TotalBreakdown(ctx, domain.TotalFilter{
+		From: month(t, "01-2025"),
+		To:   month(t, "04-2025"),
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	// weekly: 5 charges in 01-2025 (1, 8, 15, 22, 29), 4 in 02-2025, 4 in 03-2025, 5 in 04-2025
+	want := []int64{50 + 300 + 1200, 40, 40, 50 + 300}
+	for i, w := range want {
+		if items[i].Amount != w {
+			t.Fatalf("month %s: expected %d, got %d", domain.FormatMonthYear(items[i].Month), w, items[i].Amount)
+		}
+	}
+}
+
+func testTotalCost_ConvertsWithMonthlyRates(t *testing.T, st Storage) {
+	rates, repo := st.Rates, st.Subscriptions
+	ctx := context.Background()
+
+	end := month(t, "08-2025")
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "GitHub", Price: 10, Currency: "USD", UserID: testUserID, StartDate: month(t, "07-2025"), EndDate: &end}, false)
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Yandex", Price: 300, Currency: "RUB", UserID: testUserID, StartDate: month(t, "07-2025"), EndDate: &end}, false)
+
+	f := domain.TotalFilter{From: month(t, "07-2025"), To: month(t, "08-2025")}
+
+	_ = rates.Upsert(ctx, []domain.ExchangeRate{{Month: month(t, "07-2025"), Currency: "USD", Rate: 90}})
+	if _, err := repo.TotalCost(ctx, f); !errors.Is(err, service.ErrMissingExchangeRate) {
+		t.Fatalf("expected ErrMissingExchangeRate, got %v", err)
+	}
+
+	_ = rates.Upsert(ctx, []domain.ExchangeRate{{Month: month(t, "08-2025"), Currency: "USD", Rate: 100}})
+	total, err := repo.TotalCost(ctx, f)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if total.Net != 900+1000+600 {
+		t.Fatalf("expected 2500, got %d", total.Net)
+	}
+
+	f.Currency = "USD"
+	total, err = repo.TotalCost(ctx, f)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	// 20 USD + 300/90 + 300/100 = 26.33
+	if total.Net != 26 {
+		t.Fatalf("expected 26, got %d", total.Net)
+	}
+}
+
+func testTotalBreakdown_LatestRates(t *testing.T, st Storage) {
+	rates, repo := st.Rates, st.Subscriptions
+	ctx := context.Background()
+
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "GitHub", Price: 10, Currency: "USD", UserID: testUserID, StartDate: month(t, "07-2025")}, false)
+	_ = rates.Upsert(ctx, []domain.ExchangeRate{
+		{Month: month(t, "07-2025"), Currency: "USD", Rate: 90},
+		{Month: month(t, "08-2025"), Currency: "USD", Rate: 100},
+	})
+
+	f := domain.TotalFilter{From: month(t, "08-2025"), To: month(t, "10-2025")}
+	if _, err := repo.TotalBreakdown(ctx, f); !errors.Is(err, service.ErrMissingExchangeRate) {
+		t.Fatalf("expected ErrMissingExchangeRate, got %v", err)
+	}
+
+	f.LatestRates = true
+	items, err := repo.TotalBreakdown(ctx, f)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	for _, item := range items {
+		if item.Amount != 1000 {
+			t.Fatalf("%s: expected 1000, got %d", domain.FormatMonthYear(item.Month), item.Amount)
+		}
+	}
+}
+
+func testTotalCost_UsesPriceValidForEachMonth(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+
+	id, _ := repo.Create(ctx, domain.Subscription{ServiceName: "Netflix", Price: 400, UserID: testUserID, StartDate: month(t, "01-2025")}, false)
+	s, _ := repo.GetByID(ctx, id)
+	s.Price = 500
+	_, _ = repo.Update(ctx, *s, &domain.PriceChange{SubscriptionID: id, EffectiveFrom: month(t, "03-2025"), Price: 500}, false)
+
+	total, err := repo.TotalCost(ctx, domain.TotalFilter{From: month(t, "01-2025"), To: month(t, "04-2025")})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if total.Net != 400*2+500*2 {
+		t.Fatalf("expected 1800, got %d", total.Net)
+	}
+
+	prices, _ := repo.ListPrices(ctx, id)
+	if len(prices) != 2 {
+		t.Fatalf("expected 2 price changes, got %+v", prices)
+	}
+}
+
+func testEvents_RecordedForEachChange(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := domain.WithActor(domain.WithRequestID(context.Background(), "req-1"), "alice")
+
+	id, _ := repo.Create(ctx, domain.Subscription{ServiceName: "Netflix", Price: 400, UserID: testUserID, StartDate: month(t, "01-2025")}, false)
+	s, _ := repo.GetByID(ctx, id)
+	s.Price = 500
+	_, _ = repo.Update(ctx, *s, nil, false)
+	_, _ = repo.Delete(ctx, id, 0)
+
+	events, err := repo.ListEvents(ctx, domain.EventFilter{SubscriptionID: id})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	wantActions := []domain.EventAction{domain.EventDelete, domain.EventUpdate, domain.EventCreate}
+	if len(events) != len(wantActions) {
+		t.Fatalf("expected %d events, got %d", len(wantActions), len(events))
+	}
+	for i, e := range events {
+		if e.Action != wantActions[i] {
+			t.Fatalf("event %d: expected %s, got %s", i, wantActions[i], e.Action)
+		}
+		if e.RequestID == nil || *e.RequestID != "req-1" || e.Actor == nil || *e.Actor != "alice" {
+			t.Fatalf("event %d: expected request id and actor, got %+v", i, e)
+		}
+	}
+	if events[0].After != nil || events[0].Before == nil {
+		t.Fatalf("delete event must have only 'before', got %+v", events[0])
+	}
+}
+
+func testSoftDelete_RestoreAndPurge(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+
+	id, _ := repo.Create(ctx, domain.Subscription{ServiceName: "Netflix", Price: 400, UserID: testUserID, StartDate: month(t, "01-2025")}, false)
+	_, _ = repo.Delete(ctx, id, 0)
+
+	total, _ := repo.TotalCost(ctx, domain.TotalFilter{From: month(t, "01-2025"), To: month(t, "01-2025")})
+	if total.Net != 0 {
+		t.Fatalf("deleted subscription must not be counted, got %d", total.Net)
+	}
+	items, _ := repo.List(ctx, domain.ListFilter{IncludeDeleted: true})
+	if len(items) != 1 || items[0].DeletedAt == nil {
+		t.Fatalf("expected deleted subscription with include_deleted, got %+v", items)
+	}
+
+	restored, err := repo.Restore(ctx, id)
+	if err != nil || restored == nil || restored.DeletedAt != nil {
+		t.Fatalf("expected restored subscription, got %+v, %v", restored, err)
+	}
+
+	_, _ = repo.Delete(ctx, id, 0)
+	purged, err := repo.Purge(ctx, time.Now().Add(time.Hour))
+	if err != nil || purged != 1 {
+		t.Fatalf("expected 1 purged, got %d, %v", purged, err)
+	}
+	if _, err := repo.Restore(ctx, id); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if items, _ := repo.List(ctx, domain.ListFilter{IncludeDeleted: true}); len(items) != 0 {
+		t.Fatalf("expected no rows after purge, got %+v", items)
+	}
+}
+
+func testVersion_StaleUpdateAndDelete(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+
+	id, _ := repo.Create(ctx, domain.Subscription{ServiceName: "Netflix", Price: 400, UserID: testUserID, StartDate: month(t, "01-2025")}, false)
+	stale, _ := repo.GetByID(ctx, id)
+	if stale.Version != 1 {
+		t.Fatalf("expected version 1, got %d", stale.Version)
+	}
+
+	fresh := *stale
+	fresh.Price = 500
+	updated, err := repo.Update(ctx, fresh, nil, false)
+	if err != nil || updated.Version != 2 {
+		t.Fatalf("expected version 2, got %+v, %v", updated, err)
+	}
+
+	stale.Price = 600
+	if _, err := repo.Update(ctx, *stale, nil, false); !errors.Is(err, service.ErrPreconditionFailed) {
+		t.Fatalf("expected ErrPreconditionFailed, got %v", err)
+	}
+	if _, err := repo.Delete(ctx, id, 1); !errors.Is(err, service.ErrPreconditionFailed) {
+		t.Fatalf("expected ErrPreconditionFailed, got %v", err)
+	}
+	if deleted, err := repo.Delete(ctx, id, 2); err != nil || !deleted {
+		t.Fatalf("expected deleted, got %v, %v", deleted, err)
+	}
+}
+
+func testList_KeysetAndCount(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Netflix", Price: 400, UserID: testUserID, StartDate: month(t, "01-2025")}, false)
+	}
+
+	items, err := repo.List(ctx, domain.ListFilter{Limit: 10, Offset: 5, After: &domain.ListCursor{ID: 1}})
+	if err != nil || len(items) != 2 || items[0].ID != 2 {
+		t.Fatalf("expected ids 2,3 after cursor, got %+v, %v", items, err)
+	}
+	n, err := repo.Count(ctx, domain.ListFilter{After: &domain.ListCursor{ID: 1}})
+	if err != nil || n != 3 {
+		t.Fatalf("expected count 3 regardless of cursor, got %d, %v", n, err)
+	}
+}
+
+func testList_SortAndFilters(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+
+	const otherUser = "0b7e6c1a-7c1e-4d4a-9a55-2f7f6b1c9e01"
+	end := month(t, "03-2025")
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Netflix", Price: 400, UserID: testUserID, StartDate: month(t, "01-2025")}, false)
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "netology", Price: 900, UserID: otherUser, StartDate: month(t, "01-2025"), EndDate: &end}, false)
+	_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Yandex Plus", Price: 300, UserID: testUserID, StartDate: month(t, "09-2025")}, false)
+
+	byPriceDesc, _ := domain.ParseListSort("-price")
+	items, _ := repo.List(ctx, domain.ListFilter{Sort: byPriceDesc})
+	if len(items) != 3 || items[0].Price != 900 || items[2].Price != 300 {
+		t.Fatalf("expected price desc order, got %+v", items)
+	}
+
+	// keyset по цене: после 900 идут 400 и 300
+	after := domain.ListCursor{Sort: byPriceDesc.String(), Key: byPriceDesc.Key(items[0]), ID: items[0].ID}
+	items, _ = repo.List(ctx, domain.ListFilter{Sort: byPriceDesc, After: &after})
+	if len(items) != 2 || items[0].Price != 400 {
+		t.Fatalf("expected 400, 300 after cursor, got %+v", items)
+	}
+
+	prefix := "NET"
+	items, _ = repo.List(ctx, domain.ListFilter{ServiceNamePrefix: &prefix})
+	if len(items) != 2 {
+		t.Fatalf("expected 2 by case-insensitive prefix, got %+v", items)
+	}
+
+	minPrice, maxPrice := int64(350), int64(500)
+	items, _ = repo.List(ctx, domain.ListFilter{PriceMin: &minPrice, PriceMax: &maxPrice})
+	if len(items) != 1 || items[0].Price != 400 {
+		t.Fatalf("expected only price 400, got %+v", items)
+	}
+
+	items, _ = repo.List(ctx, domain.ListFilter{UserIDs: []string{otherUser, "ffffffff-ffff-ffff-ffff-ffffffffffff"}})
+	if len(items) != 1 || items[0].UserID != otherUser {
+		t.Fatalf("expected subscription of other user, got %+v", items)
+	}
+
+	for status, want := range map[domain.ListStatus]int64{
+		domain.StatusActive:   400,
+		domain.StatusEnded:    900,
+		domain.StatusUpcoming: 300,
+	} {
+		items, _ = repo.List(ctx, domain.ListFilter{Status: status, StatusAt: month(t, "05-2025")})
+		if len(items) != 1 || items[0].Price != want {
+			t.Fatalf("%s: expected price %d, got %+v", status, want, items)
+		}
+	}
+}
+
+func testExport_IgnoresPagination(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		_, _ = repo.Create(ctx, domain.Subscription{ServiceName: "Netflix", Price: int64(100 * i), UserID: testUserID, StartDate: month(t, "01-2025")}, false)
+	}
+
+	byPriceDesc, _ := domain.ParseListSort("-price")
+	var prices []int64
+	err := repo.Export(ctx, domain.ListFilter{Limit: 1, Offset: 1, Sort: byPriceDesc}, func(s domain.Subscription) error {
+		prices = append(prices, s.Price)
+		return nil
+	})
+	if err != nil || len(prices) != 3 || prices[0] != 300 {
+		t.Fatalf("expected all 3 rows by price desc, got %v, %v", prices, err)
+	}
+}
+
+func testStrictMode_RejectsOverlapsOnWrite(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+
+	end := domain.MonthEndUTC(month(t, "06-2025"))
+	if _, err := repo.Create(ctx, domain.Subscription{ServiceName: "Netflix", Price: 400, UserID: testUserID, StartDate: month(t, "01-2025"), EndDate: &end}, true); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	overlapping := domain.Subscription{ServiceName: "Netflix", Price: 400, UserID: testUserID, StartDate: month(t, "04-2025")}
+	if _, err := repo.Create(ctx, overlapping, true); !errors.Is(err, service.ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	if n, _ := repo.Count(ctx, domain.ListFilter{}); n != 1 {
+		t.Fatalf("expected nothing stored in strict mode, got %d subscriptions", n)
+	}
+
+	overlapping.StartDate = month(t, "07-2025")
+	second, err := repo.Create(ctx, overlapping, true)
+	if err != nil {
+		t.Fatalf("expected adjacent subscription to be created, got %v", err)
+	}
+
+	// сдвиг начала назад пересекается с первой подпиской; проверка исключает саму подписку
+	s, _ := repo.GetByID(ctx, second)
+	s.StartDate = month(t, "06-2025")
+	if _, err := repo.Update(ctx, *s, nil, true); !errors.Is(err, service.ErrConflict) {
+		t.Fatalf("expected ErrConflict on update, got %v", err)
+	}
+	s.Price = 500
+	s.StartDate = month(t, "07-2025")
+	if _, err := repo.Update(ctx, *s, nil, true); err != nil {
+		t.Fatalf("expected update without overlap, got %v", err)
+	}
+
+	// без строгого режима пересечения разрешены
+	if _, err := repo.Create(ctx, domain.Subscription{ServiceName: "Netflix", Price: 400, UserID: testUserID, StartDate: month(t, "03-2025")}, false); err != nil {
+		t.Fatalf("lenient: expected created, got %v", err)
+	}
+}
+
+func testDuplicates_OverlappingRangesOfSameService(t *testing.T, st Storage) {
+	repo := st.Subscriptions
+	ctx := context.Background()
+
+	end := func(s string) *time.Time {
+		m := domain.MonthEndUTC(month(t, s))
+		return &m
+	}
+	subs := []domain.Subscription{
+		{ServiceName: "Netflix", Price: 400, UserID: testUserID, StartDate: month(t, "01-2025"), EndDate: end("06-2025")},
+		{ServiceName: "Netflix", Price: 400, UserID: testUserID, StartDate: month(t, "04-2025")},
+		// тот же сервис у другого пользователя — не дубль
+		{ServiceName: "Netflix", Price: 400, UserID: "11111111-2bf1-4721-ae6f-7636e79a0cba", StartDate: month(t, "04-2025")},
+		{ServiceName: "Spotify", Price: 200, UserID: testUserID, StartDate: month(t, "01-2025")},
+	}
+	for _, s := range subs {
+		if _, err := repo.Create(ctx, s, false); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	got, err := repo.Duplicates(ctx, domain.DuplicateFilter{})
+	if err != nil || len(got) != 1 {
+		t.Fatalf("expected 1 overlap, got %+v, %v", got, err)
+	}
+	o := got[0]
+	if o.FirstID != 1 || o.SecondID != 2 || !o.From.Equal(month(t, "04-2025")) || o.To == nil || !o.To.Equal(*end("06-2025")) {
+		t.Fatalf("unexpected overlap %+v", o)
+	}
+
+	// подписка, начинающаяся на следующий день после окончания, не пересекается
+	next := domain.Subscription{ID: 10, ServiceName: "Netflix", UserID: testUserID, StartDate: month(t, "07-2025")}
+	others, err := repo.Overlapping(ctx, next)
+	if err != nil || len(others) != 1 || others[0].ID != 2 {
+		t.Fatalf("expected overlap with 2 only, got %+v, %v", others, err)
+	}
+
+	if _, err := repo.Delete(ctx, 2, 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	got, err = repo.Duplicates(ctx, domain.DuplicateFilter{})
+	if err != nil || len(got) != 0 {
+		t.Fatalf("expected deleted subscription to be ignored, got %+v, %v", got, err)
+	}
+}
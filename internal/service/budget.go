@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"subscription_service/internal/domain"
+)
+
+// BudgetNotifier доставляет пользователю уведомления о превышении бюджета.
+type BudgetNotifier interface {
+	NotifyBudgetExceeded(ctx context.Context, a domain.BudgetAlert) error
+}
+
+type BudgetService struct {
+	repo     BudgetRepository
+	subs     *SubscriptionService
+	notifier BudgetNotifier
+	now      func() time.Time
+}
+
+// NewBudgetService создаёт сервис; без notifier превышения только записываются.
+func NewBudgetService(repo BudgetRepository, subs *SubscriptionService, notifier BudgetNotifier) *BudgetService {
+	return &BudgetService{repo: repo, subs: subs, notifier: notifier, now: time.Now}
+}
+
+type CreateBudgetRequest struct {
+	Amount   int64   // лимит на месяц
+	Currency string  // ISO 4217, "" = RUB
+	Tag      *string // учитывать только подписки с этим тегом; nil = все
+}
+
+type UpdateBudgetRequest struct {
+	Amount   *int64
+	Currency *string
+	Tag      *string // "" — учитывать все подписки
+}
+
+func (s *BudgetService) Create(ctx context.Context, userID string, req CreateBudgetRequest) (*domain.Budget, error) {
+	b := domain.Budget{UserID: userID, Amount: req.Amount, Currency: req.Currency, Tag: req.Tag}
+	if err := normalizeBudget(&b); err != nil {
+		return nil, err
+	}
+
+	id, err := s.repo.Create(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Get возвращает бюджет пользователя; чужой бюджет не находится.
+func (s *BudgetService) Get(ctx context.Context, userID string, id int64) (*domain.Budget, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: invalid id", ErrInvalidInput)
+	}
+	b, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil || b.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return b, nil
+}
+
+func (s *BudgetService) Update(ctx context.Context, userID string, id int64, req UpdateBudgetRequest) (*domain.Budget, error) {
+	existing, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Amount != nil {
+		existing.Amount = *req.Amount
+	}
+	if req.Currency != nil {
+		if *req.Currency == "" {
+			return nil, fmt.Errorf("%w: currency empty", ErrInvalidInput)
+		}
+		existing.Currency = *req.Currency
+	}
+	if req.Tag != nil {
+		existing.Tag = req.Tag
+	}
+	if err := normalizeBudget(existing); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.Update(ctx, *existing)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrNotFound
+	}
+	return updated, nil
+}
+
+func (s *BudgetService) Delete(ctx context.Context, userID string, id int64) error {
+	if _, err := s.Get(ctx, userID, id); err != nil {
+		return err
+	}
+	deleted, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *BudgetService) List(ctx context.Context, userID string) ([]domain.Budget, error) {
+	return s.repo.List(ctx, userID)
+}
+
+// Alerts возвращает записанные превышения бюджетов пользователя.
+func (s *BudgetService) Alerts(ctx context.Context, f domain.BudgetAlertFilter) ([]domain.BudgetAlert, error) {
+	if f.UserID == "" {
+		return nil, fmt.Errorf("%w: user_id is required", ErrInvalidInput)
+	}
+	return s.repo.ListAlerts(ctx, f)
+}
+
+// Evaluate сверяет все бюджеты с расходами текущего месяца (как TotalCost) и записывает превышения,
+// по одному на бюджет и месяц. Затем отправляет через notifier ещё не доставленные уведомления.
+// Возвращает число новых превышений; ошибки по отдельным бюджетам не прерывают проверку остальных.
+func (s *BudgetService) Evaluate(ctx context.Context) (int, error) {
+	month := domain.MonthStartUTC(s.now().UTC())
+
+	budgets, err := s.repo.List(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		recorded int
+		errs     []error
+	)
+	for _, b := range budgets {
+		total, err := s.subs.TotalCost(ctx, b.Filter(month, month))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("budget %d: %w", b.ID, err))
+			continue
+		}
+		if total.Net <= b.Amount {
+			continue
+		}
+
+		alert, err := s.repo.AddAlert(ctx, domain.BudgetOverrun{
+			BudgetID: b.ID,
+			UserID:   b.UserID,
+			Month:    month,
+			Limit:    b.Amount,
+			Spent:    total.Net,
+			Currency: b.Currency,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("budget %d: %w", b.ID, err))
+			continue
+		}
+		if alert != nil {
+			recorded++
+		}
+	}
+
+	if err := s.deliver(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	return recorded, errors.Join(errs...)
+}
+
+// maxAlertsPerDelivery — сколько уведомлений отправляется за один вызов Evaluate, остальные — в следующий.
+const maxAlertsPerDelivery = 200
+
+// deliver отправляет недоставленные уведомления; неудачные останутся до следующего раза.
+func (s *BudgetService) deliver(ctx context.Context) error {
+	if s.notifier == nil {
+		return nil
+	}
+
+	pending, err := s.repo.ListAlerts(ctx, domain.BudgetAlertFilter{Pending: true, Limit: maxAlertsPerDelivery})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, a := range pending {
+		if err := s.notifier.NotifyBudgetExceeded(ctx, a); err != nil {
+			errs = append(errs, fmt.Errorf("notify alert %d: %w", a.ID, err))
+			continue
+		}
+		if err := s.repo.MarkNotified(ctx, a.ID, s.now().UTC()); err != nil {
+			errs = append(errs, fmt.Errorf("mark alert %d: %w", a.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// BudgetCheck — результат сверки созданной или изменённой подписки с бюджетами её участников.
+type BudgetCheck struct {
+	// Warnings — месяцы, в которых изменение вывело расходы за бюджет или увеличило превышение.
+	Warnings []domain.BudgetOverrun
+	// Err — сверить не удалось (например, нет курса валюты); само изменение при этом сохранено.
+	Err error
+}
+
+// budgetBaseline — расходы по бюджетам до изменения подписки.
+type budgetBaseline struct {
+	budgets []domain.Budget               // бюджеты участников, которые учитывают подписку
+	spent   map[int64]map[time.Time]int64 // id бюджета → месяц → сумма
+}
+
+// budgetBaseline запоминает бюджеты участников sub (в том виде, в каком sub будет сохранена) и расходы
+// по ним до сохранения изменения, чтобы budgetWarnings сравнил с ними расходы после, не перечитывая бюджеты.
+func (s *SubscriptionService) budgetBaseline(ctx context.Context, sub domain.Subscription) (budgetBaseline, error) {
+	budgets, err := s.subscriptionBudgets(ctx, sub)
+	if err != nil {
+		return budgetBaseline{}, err
+	}
+	baseline := budgetBaseline{budgets: budgets, spent: map[int64]map[time.Time]int64{}}
+	err = s.eachBudgetMonth(ctx, sub, budgets, func(b domain.Budget, m domain.MonthlyCost) {
+		if baseline.spent[b.ID] == nil {
+			baseline.spent[b.ID] = map[time.Time]int64{}
+		}
+		baseline.spent[b.ID][m.Month] = m.Amount
+	})
+	return baseline, err
+}
+
+// budgetWarnings возвращает месяцы, в которых sub что-то списывает, расходы участника превышают его бюджет,
+// и до изменения (baseline) либо укладывались в бюджет, либо превышали его меньше.
+// Правка, которая не меняет расходов или уменьшает их, предупреждений не даёт.
+func (s *SubscriptionService) budgetWarnings(ctx context.Context, sub domain.Subscription, baseline budgetBaseline) ([]domain.BudgetOverrun, error) {
+	var out []domain.BudgetOverrun
+	err := s.eachBudgetMonth(ctx, sub, baseline.budgets, func(b domain.Budget, m domain.MonthlyCost) {
+		if m.Amount <= b.Amount || !sub.ChargedIn(m.Month) {
+			return
+		}
+		if before, ok := baseline.spent[b.ID][m.Month]; ok && before > b.Amount && m.Amount <= before {
+			return
+		}
+		out = append(out, domain.BudgetOverrun{
+			BudgetID: b.ID,
+			UserID:   b.UserID,
+			Month:    m.Month,
+			Limit:    b.Amount,
+			Spent:    m.Amount,
+			Currency: b.Currency,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// subscriptionBudgets возвращает бюджеты участников sub, которые её учитывают (см. domain.Budget.Counts).
+func (s *SubscriptionService) subscriptionBudgets(ctx context.Context, sub domain.Subscription) ([]domain.Budget, error) {
+	if s.budgets == nil {
+		return nil, nil
+	}
+
+	var out []domain.Budget
+	for _, share := range sub.Shares() {
+		budgets, err := s.budgets.List(ctx, share.UserID)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range budgets {
+			if b.Counts(sub) {
+				out = append(out, b)
+			}
+		}
+	}
+	return out, nil
+}
+
+// eachBudgetMonth вызывает fn для расходов каждого месяца по каждому из budgets.
+// Проверяются DefaultForecastMonths месяцев начиная с текущего (или с начала подписки, если она ещё не началась),
+// как в Forecast, но не дальше её окончания.
+func (s *SubscriptionService) eachBudgetMonth(ctx context.Context, sub domain.Subscription, budgets []domain.Budget, fn func(domain.Budget, domain.MonthlyCost)) error {
+	if len(budgets) == 0 {
+		return nil
+	}
+
+	from := domain.MonthStartUTC(s.now().UTC())
+	if start := domain.MonthStartUTC(sub.StartDate); start.After(from) {
+		from = start
+	}
+	to := from.AddDate(0, domain.DefaultForecastMonths-1, 0)
+	if sub.EndDate != nil && sub.EndDate.Before(to) {
+		to = domain.MonthStartUTC(*sub.EndDate)
+	}
+	if to.Before(from) {
+		return nil
+	}
+
+	for _, b := range budgets {
+		f := b.Filter(from, to)
+		f.LatestRates = true
+		months, err := s.TotalBreakdown(ctx, f)
+		if err != nil {
+			return err
+		}
+		for _, m := range months {
+			fn(b, m)
+		}
+	}
+	return nil
+}
+
+// normalizeBudget проверяет лимит, валюту и нормализует тег.
+func normalizeBudget(b *domain.Budget) error {
+	if b.UserID == "" {
+		return fmt.Errorf("%w: user_id is required", ErrInvalidInput)
+	}
+	if b.Amount <= 0 {
+		return fmt.Errorf("%w: amount must be > 0", ErrInvalidInput)
+	}
+	currency, err := domain.ParseCurrency(b.Currency)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	b.Currency = currency
+
+	if b.Tag != nil {
+		tags, err := domain.NormalizeTags([]string{*b.Tag})
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		if len(tags) == 0 {
+			b.Tag = nil
+		} else {
+			b.Tag = &tags[0]
+		}
+	}
+	return nil
+}
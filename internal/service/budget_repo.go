@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+	"subscription_service/internal/domain"
+	"time"
+)
+
+type BudgetRepository interface {
+	Create(ctx context.Context, b domain.Budget) (int64, error)
+	GetByID(ctx context.Context, id int64) (*domain.Budget, error)
+	// Update — nil, если бюджета нет.
+	Update(ctx context.Context, b domain.Budget) (*domain.Budget, error)
+	Delete(ctx context.Context, id int64) (bool, error)
+	// List возвращает бюджеты пользователя по id, а с пустым userID — все бюджеты.
+	List(ctx context.Context, userID string) ([]domain.Budget, error)
+
+	// AddAlert записывает превышение; nil, если за этот месяц по бюджету оно уже записано.
+	AddAlert(ctx context.Context, o domain.BudgetOverrun) (*domain.BudgetAlert, error)
+	// ListAlerts возвращает превышения от новых к старым.
+	ListAlerts(ctx context.Context, f domain.BudgetAlertFilter) ([]domain.BudgetAlert, error)
+	// MarkNotified отмечает, что уведомление о превышении доставлено.
+	MarkNotified(ctx context.Context, alertID int64, at time.Time) error
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"subscription_service/internal/domain"
+)
+
+var (
+	ErrServiceNameTaken = errors.New("service name or alias already exists")
+	ErrServiceInUse     = errors.New("service is used by subscriptions")
+)
+
+type CatalogService struct {
+	repo CatalogRepository
+}
+
+func NewCatalogService(repo CatalogRepository) *CatalogService {
+	return &CatalogService{repo: repo}
+}
+
+type CreateServiceRequest struct {
+	Name         string
+	Aliases      []string
+	Category     *string
+	DefaultPrice *int64
+}
+
+type UpdateServiceRequest struct {
+	Name         *string
+	Aliases      *[]string // заменяет список целиком
+	Category     *string   // "" — очистить
+	DefaultPrice *int64    // 0 — очистить
+}
+
+func (s *CatalogService) Create(ctx context.Context, req CreateServiceRequest) (*domain.Service, error) {
+	svc := domain.Service{Name: req.Name, Aliases: req.Aliases, Category: req.Category, DefaultPrice: req.DefaultPrice}
+	if err := normalizeService(&svc); err != nil {
+		return nil, err
+	}
+
+	id, err := s.repo.Create(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *CatalogService) GetByID(ctx context.Context, id int64) (*domain.Service, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: invalid id", ErrInvalidInput)
+	}
+	svc, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if svc == nil {
+		return nil, ErrNotFound
+	}
+	return svc, nil
+}
+
+func (s *CatalogService) Update(ctx context.Context, id int64, req UpdateServiceRequest) (*domain.Service, error) {
+	existing, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.Aliases != nil {
+		existing.Aliases = *req.Aliases
+	}
+	if req.Category != nil {
+		existing.Category = req.Category
+	}
+	if req.DefaultPrice != nil {
+		existing.DefaultPrice = req.DefaultPrice
+	}
+	if err := normalizeService(existing); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.Update(ctx, *existing)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrNotFound
+	}
+	return updated, nil
+}
+
+func (s *CatalogService) Delete(ctx context.Context, id int64) error {
+	deleted, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *CatalogService) List(ctx context.Context, f domain.ServiceFilter) ([]domain.Service, error) {
+	return s.repo.List(ctx, f)
+}
+
+// normalizeService чистит имя и алиасы, убирает повторы и алиасы, совпадающие с именем.
+func normalizeService(svc *domain.Service) error {
+	svc.Name = domain.CleanServiceName(svc.Name)
+	if svc.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	if svc.DefaultPrice != nil {
+		switch {
+		case *svc.DefaultPrice == 0:
+			svc.DefaultPrice = nil
+		case *svc.DefaultPrice < 0:
+			return fmt.Errorf("%w: default_price must be > 0", ErrInvalidInput)
+		}
+	}
+	if svc.Category != nil {
+		if c := domain.CleanServiceName(*svc.Category); c != "" {
+			svc.Category = &c
+		} else {
+			svc.Category = nil
+		}
+	}
+
+	seen := map[string]bool{domain.ServiceNameKey(svc.Name): true}
+	aliases := make([]string, 0, len(svc.Aliases))
+	for _, a := range svc.Aliases {
+		a = domain.CleanServiceName(a)
+		key := domain.ServiceNameKey(a)
+		if a == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		aliases = append(aliases, a)
+	}
+	svc.Aliases = aliases
+	return nil
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+	"subscription_service/internal/domain"
+)
+
+type CatalogRepository interface {
+	// Create возвращает ErrServiceNameTaken, если имя или алиас уже заняты.
+	Create(ctx context.Context, s domain.Service) (int64, error)
+	GetByID(ctx context.Context, id int64) (*domain.Service, error)
+	// Update меняет запись каталога и переписывает service_name у её подписок.
+	// nil, если записи нет; ErrServiceNameTaken, если имя или алиас заняты.
+	Update(ctx context.Context, s domain.Service) (*domain.Service, error)
+	// Delete возвращает ErrServiceInUse, если на запись ссылаются подписки.
+	Delete(ctx context.Context, id int64) (bool, error)
+	List(ctx context.Context, f domain.ServiceFilter) ([]domain.Service, error)
+	// Resolve ищет запись по имени или алиасу (см. domain.ServiceNameKey); nil, если не нашлась.
+	Resolve(ctx context.Context, name string) (*domain.Service, error)
+	// Ensure — Resolve, а если записи нет, создаёт её с этим именем.
+	Ensure(ctx context.Context, name string) (*domain.Service, error)
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"subscription_service/internal/domain"
+)
+
+// maxDiscountCodeLen — ограничение на длину промокода.
+const maxDiscountCodeLen = 64
+
+type AddDiscountRequest struct {
+	Kind  string  // percent | fixed
+	Value int64   // процент 1..100 или сумма в месяц в валюте подписки
+	Code  *string // промокод, только для информации
+	From  string  // "MM-YYYY", первый месяц скидки
+	// Until ("MM-YYYY") — последний месяц скидки; вместо него можно передать Months.
+	// Без обоих скидка бессрочная.
+	Until  *string
+	Months *int
+}
+
+// AddDiscount добавляет подписке скидку. Скидки применяются к расчётам стоимости помесячно, см. domain.DiscountAt.
+func (s *SubscriptionService) AddDiscount(ctx context.Context, id int64, req AddDiscountRequest) (domain.Discount, error) {
+	if id <= 0 {
+		return domain.Discount{}, fmt.Errorf("%w: invalid id", ErrInvalidInput)
+	}
+	d, err := newDiscount(req)
+	if err != nil {
+		return domain.Discount{}, err
+	}
+
+	existing, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return domain.Discount{}, err
+	}
+	if existing == nil {
+		return domain.Discount{}, ErrNotFound
+	}
+	if err := checkDiscountInRange(*existing, d); err != nil {
+		return domain.Discount{}, err
+	}
+
+	d.SubscriptionID = id
+	return s.repo.AddDiscount(ctx, d)
+}
+
+// checkDiscountInRange проверяет, что скидка начинается внутри срока подписки.
+func checkDiscountInRange(sub domain.Subscription, d domain.Discount) error {
+	if d.From.Before(domain.MonthStartUTC(sub.StartDate)) {
+		return fmt.Errorf("%w: discount starts before start_date", ErrInvalidInput)
+	}
+	if sub.EndDate != nil && d.From.After(*sub.EndDate) {
+		return fmt.Errorf("%w: discount starts after end_date", ErrInvalidInput)
+	}
+	return nil
+}
+
+// checkDiscountsInRange применяет checkDiscountInRange ко всем скидкам подписки после смены её дат.
+func (s *SubscriptionService) checkDiscountsInRange(ctx context.Context, sub domain.Subscription) error {
+	discounts, err := s.repo.ListDiscounts(ctx, sub.ID)
+	if err != nil {
+		return err
+	}
+	for _, d := range discounts {
+		if err := checkDiscountInRange(sub, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newDiscount(req AddDiscountRequest) (domain.Discount, error) {
+	kind, err := domain.ParseDiscountKind(req.Kind)
+	if err != nil {
+		return domain.Discount{}, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if req.Value <= 0 || (kind == domain.DiscountPercent && req.Value > 100) {
+		return domain.Discount{}, fmt.Errorf("%w: value must be 1..100 for percent and > 0 for fixed", ErrInvalidInput)
+	}
+
+	d := domain.Discount{Kind: kind, Value: req.Value}
+	if req.Code != nil {
+		code := strings.TrimSpace(*req.Code)
+		if len(code) > maxDiscountCodeLen {
+			return domain.Discount{}, fmt.Errorf("%w: code longer than %d characters", ErrInvalidInput, maxDiscountCodeLen)
+		}
+		if code != "" {
+			d.Code = &code
+		}
+	}
+
+	if d.From, err = parseMonthYear(req.From); err != nil {
+		return domain.Discount{}, fmt.Errorf("%w: from must be MM-YYYY", ErrInvalidInput)
+	}
+	if req.Until != nil && req.Months != nil {
+		return domain.Discount{}, fmt.Errorf("%w: until cannot be combined with months", ErrInvalidInput)
+	}
+	var until *time.Time
+	switch {
+	case req.Until != nil:
+		u, err := parseMonthYear(*req.Until)
+		if err != nil {
+			return domain.Discount{}, fmt.Errorf("%w: until must be MM-YYYY", ErrInvalidInput)
+		}
+		if u.Before(d.From) {
+			return domain.Discount{}, fmt.Errorf("%w: until before from", ErrInvalidDateRange)
+		}
+		until = &u
+	case req.Months != nil:
+		if *req.Months <= 0 {
+			return domain.Discount{}, fmt.Errorf("%w: months must be > 0", ErrInvalidInput)
+		}
+		u := d.From.AddDate(0, *req.Months-1, 0)
+		until = &u
+	}
+	d.Until = until
+	return d, nil
+}
+
+// Discounts возвращает скидки подписки.
+func (s *SubscriptionService) Discounts(ctx context.Context, id int64) ([]domain.Discount, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: invalid id", ErrInvalidInput)
+	}
+
+	existing, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrNotFound
+	}
+
+	return s.repo.ListDiscounts(ctx, id)
+}
+
+func (s *SubscriptionService) DeleteDiscount(ctx context.Context, id, discountID int64) error {
+	if id <= 0 || discountID <= 0 {
+		return fmt.Errorf("%w: invalid id", ErrInvalidInput)
+	}
+
+	deleted, err := s.repo.DeleteDiscount(ctx, id, discountID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
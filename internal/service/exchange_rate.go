@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"subscription_service/internal/domain"
+)
+
+type ExchangeRateService struct {
+	repo ExchangeRateRepository
+}
+
+func NewExchangeRateService(repo ExchangeRateRepository) *ExchangeRateService {
+	return &ExchangeRateService{repo: repo}
+}
+
+type UpsertExchangeRateRequest struct {
+	Month    string // "MM-YYYY"
+	Currency string // ISO 4217
+	Rate     float64
+}
+
+// Upsert загружает курсы пачкой: либо сохраняются все, либо ни одного.
+func (s *ExchangeRateService) Upsert(ctx context.Context, reqs []UpsertExchangeRateRequest) error {
+	if len(reqs) == 0 {
+		return fmt.Errorf("%w: no rates provided", ErrInvalidInput)
+	}
+
+	rates := make([]domain.ExchangeRate, 0, len(reqs))
+	for i, req := range reqs {
+		month, err := domain.ParseMonthYear(req.Month)
+		if err != nil {
+			return fmt.Errorf("%w: rate #%d: invalid month", ErrInvalidInput, i+1)
+		}
+		currency, err := domain.ParseCurrency(req.Currency)
+		if err != nil || req.Currency == "" {
+			return fmt.Errorf("%w: rate #%d: invalid currency", ErrInvalidInput, i+1)
+		}
+		if currency == domain.BaseCurrency {
+			return fmt.Errorf("%w: rate #%d: %s is the base currency", ErrInvalidInput, i+1, domain.BaseCurrency)
+		}
+		if req.Rate <= 0 {
+			return fmt.Errorf("%w: rate #%d: rate must be > 0", ErrInvalidInput, i+1)
+		}
+		rates = append(rates, domain.ExchangeRate{Month: month, Currency: currency, Rate: req.Rate})
+	}
+
+	return s.repo.Upsert(ctx, rates)
+}
+
+func (s *ExchangeRateService) List(ctx context.Context, f domain.ExchangeRateFilter) ([]domain.ExchangeRate, error) {
+	return s.repo.List(ctx, f)
+}
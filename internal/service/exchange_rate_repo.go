@@ -0,0 +1,11 @@
+package service
+
+import (
+	"context"
+	"subscription_service/internal/domain"
+)
+
+type ExchangeRateRepository interface {
+	Upsert(ctx context.Context, rates []domain.ExchangeRate) error
+	List(ctx context.Context, f domain.ExchangeRateFilter) ([]domain.ExchangeRate, error)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"subscription_service/internal/domain"
+	"time"
+)
+
+var (
+	ErrIdempotencyKeyReused  = errors.New("idempotency key reused with a different request")
+	ErrIdempotencyInProgress = errors.New("request with this idempotency key is in progress")
+)
+
+const (
+	// DefaultIdempotencyTTL — сколько хранится ответ, если TTL не задан в конфиге.
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// IdempotencyLease — на сколько занимается ключ до завершения запроса.
+	// Если процесс упал, не вызвав Complete или Release, ключ освободится по истечении аренды, а не TTL.
+	IdempotencyLease        = time.Minute
+	maxIdempotencyKeyLength = 255
+)
+
+type IdempotencyService struct {
+	repo IdempotencyRepository
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+// NewIdempotencyService создаёт сервис; ttl <= 0 заменяется на DefaultIdempotencyTTL.
+func NewIdempotencyService(repo IdempotencyRepository, ttl time.Duration) *IdempotencyService {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return &IdempotencyService{repo: repo, ttl: ttl, now: time.Now}
+}
+
+// HashRequest возвращает отпечаток тела запроса для сравнения повторов.
+func HashRequest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Begin занимает ключ перед выполнением запроса.
+// nil, nil — ключ свободен, запрос нужно выполнить и затем вызвать Complete или Release.
+// Запись — запрос с этим ключом уже выполнен, нужно вернуть сохранённый ответ.
+func (s *IdempotencyService) Begin(ctx context.Context, key, requestHash string) (*domain.IdempotencyRecord, error) {
+	key = strings.TrimSpace(key)
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return nil, fmt.Errorf("%w: invalid Idempotency-Key", ErrInvalidInput)
+	}
+
+	rec, reserved, err := s.repo.Reserve(ctx, key, requestHash, s.now().Add(IdempotencyLease))
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+	if rec == nil {
+		return nil, ErrIdempotencyInProgress
+	}
+	if rec.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if !rec.Completed() {
+		return nil, ErrIdempotencyInProgress
+	}
+	return rec, nil
+}
+
+// Complete сохраняет ответ вместе с заголовками headers на время TTL.
+func (s *IdempotencyService) Complete(ctx context.Context, key, requestHash string, statusCode int, headers map[string]string, response []byte) error {
+	return s.repo.Complete(ctx, domain.IdempotencyRecord{
+		Key:         strings.TrimSpace(key),
+		RequestHash: requestHash,
+		StatusCode:  &statusCode,
+		Headers:     headers,
+		Response:    response,
+		ExpiresAt:   s.now().Add(s.ttl),
+	})
+}
+
+func (s *IdempotencyService) Release(ctx context.Context, key, requestHash string) error {
+	return s.repo.Release(ctx, strings.TrimSpace(key), requestHash)
+}
+
+// PurgeExpired удаляет истёкшие ключи.
+func (s *IdempotencyService) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpired(ctx, s.now())
+}
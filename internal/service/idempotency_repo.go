@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+	"subscription_service/internal/domain"
+	"time"
+)
+
+type IdempotencyRepository interface {
+	// Reserve атомарно занимает ключ до expiresAt и возвращает true.
+	// Если ключ уже занят и не истёк — возвращает существующую запись и false
+	// (nil, false — запись успели освободить, ключ считается занятым).
+	Reserve(ctx context.Context, key, requestHash string, expiresAt time.Time) (*domain.IdempotencyRecord, bool, error)
+	// Complete сохраняет ответ для повторов и продлевает запись до rec.ExpiresAt.
+	// Запись, которую уже перехватил запрос с другим отпечатком, не меняется.
+	Complete(ctx context.Context, rec domain.IdempotencyRecord) error
+	// Release освобождает ключ, если запрос завершился ошибкой и его можно повторить.
+	// Как и Complete, не трогает запись, которую уже перехватил запрос с другим отпечатком.
+	Release(ctx context.Context, key, requestHash string) error
+	// DeleteExpired удаляет записи, истёкшие к now, и возвращает их количество.
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
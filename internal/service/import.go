@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"subscription_service/internal/domain"
+)
+
+// MaxImportRows ограничивает размер одного импорта: все строки вставляются одной транзакцией.
+const MaxImportRows = 10000
+
+// ImportRow — строка файла импорта. Err задаётся, если строку не удалось разобрать.
+type ImportRow struct {
+	Line    int
+	Request CreateSubscriptionRequest
+	Err     error
+}
+
+type ImportRowResult struct {
+	Line  int
+	ID    *int64 // nil, если строка не импортирована
+	Error string
+}
+
+type ImportResult struct {
+	Imported int
+	Failed   int
+	Rows     []ImportRowResult
+}
+
+// Import проверяет строки по правилам Create и вставляет корректные одной транзакцией.
+// strict — всё или ничего: при ошибке хотя бы в одной строке ничего не вставляется.
+func (s *SubscriptionService) Import(ctx context.Context, rows []ImportRow, strict bool) (*ImportResult, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: no rows to import", ErrInvalidInput)
+	}
+	if len(rows) > MaxImportRows {
+		return nil, fmt.Errorf("%w: too many rows (max %d)", ErrInvalidInput, MaxImportRows)
+	}
+
+	result := &ImportResult{Rows: make([]ImportRowResult, len(rows))}
+	valid := make([]domain.Subscription, 0, len(rows))
+	validIdx := make([]int, 0, len(rows))
+
+	for i, row := range rows {
+		result.Rows[i].Line = row.Line
+
+		var sub domain.Subscription
+		err := row.Err
+		if err == nil {
+			sub, err = newSubscription(row.Request)
+		}
+		if err == nil {
+			// новые имена заводит в каталоге CreateBatch в одной транзакции со вставкой
+			err = s.applyCatalog(ctx, &sub, false)
+			if err != nil && !errors.Is(err, ErrInvalidInput) {
+				return nil, err
+			}
+		}
+		if err != nil {
+			result.Rows[i].Error = err.Error()
+			result.Failed++
+			continue
+		}
+		valid = append(valid, sub)
+		validIdx = append(validIdx, i)
+	}
+
+	if len(valid) == 0 || (strict && result.Failed > 0) {
+		return result, nil
+	}
+
+	ids, err := s.repo.CreateBatch(ctx, valid)
+	if err != nil {
+		return nil, err
+	}
+	for k, i := range validIdx {
+		id := ids[k]
+		result.Rows[i].ID = &id
+	}
+	result.Imported = len(ids)
+	return result, nil
+}
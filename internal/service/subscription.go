@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"subscription_service/internal/domain"
 	"time"
 )
@@ -12,25 +13,47 @@ var (
 	ErrNotFound         = errors.New("not found")
 	ErrInvalidInput     = errors.New("invalid input")
 	ErrInvalidDateRange = errors.New("invalid date range")
+
+	ErrMissingExchangeRate = errors.New("missing exchange rate")
+	ErrPreconditionFailed  = errors.New("version mismatch")
+
+	ErrAlreadyPaused = errors.New("subscription is already paused in this period")
+	ErrNotPaused     = errors.New("subscription is not paused")
+
+	// ErrConflict — подписка дублирует другую: тот же плательщик и сервис в те же дни.
+	ErrConflict = errors.New("conflict")
 )
 
 type SubscriptionService struct {
-	repo SubscriptionRepository
+	repo    SubscriptionRepository
+	catalog CatalogRepository
+	budgets BudgetRepository // для предупреждений о превышении бюджета; nil — без проверки
+	now     func() time.Time
 }
 
-func NewSubscriptionService(repo SubscriptionRepository) *SubscriptionService {
-	return &SubscriptionService{repo: repo}
+func NewSubscriptionService(repo SubscriptionRepository, catalog CatalogRepository, budgets BudgetRepository) *SubscriptionService {
+	return &SubscriptionService{repo: repo, catalog: catalog, budgets: budgets, now: time.Now}
 }
 
 type CreateSubscriptionRequest struct {
-	ServiceName string
-	Price       int64
-	UserID      string
-	StartDate   string  // "MM-YYYY"
-	EndDate     *string // nil = не задана
+	ServiceName   string
+	Price         int64
+	Currency      string // ISO 4217, "" = RUB
+	BillingPeriod string // "" = monthly
+	UserID        string
+	StartDate     string  // "MM-YYYY" или "YYYY-MM-DD"
+	EndDate       *string // "MM-YYYY" (месяц включительно) или "YYYY-MM-DD" (последний день); nil = не задана
+	TrialEnd      *string // "MM-YYYY", последний бесплатный месяц; nil = без пробного периода
+	Tags          []string
+	// Members делят стоимость по весам; пусто — платит и пользуется только UserID.
+	Members []domain.SubscriptionMember
+
+	// RejectOverlaps — строгий режим: подписка, дублирующая существующую, не создаётся (ErrConflict).
+	RejectOverlaps bool
 }
 
-// PATCH: end_date — 3 состояния: не прислали / прислали null / прислали значение
+// PATCH: end_date — 3 состояния: не прислали / прислали null / прислали значение.
+// Так же передаётся trial_end.
 type EndDateUpdate struct {
 	Provided bool
 	Value    *string
@@ -51,42 +74,219 @@ func EndDateSetValue(v string) EndDateUpdate {
 type UpdateSubscriptionRequest struct {
 	ServiceName *string
 	Price       *int64
-	UserID      *string
-	StartDate   *string
-	EndDate     EndDateUpdate
+	// PriceEffectiveFrom ("MM-YYYY") — с какого месяца действует новая Price.
+	// Если не задан, цена меняется за весь срок подписки.
+	PriceEffectiveFrom *string
+	Currency           *string
+	BillingPeriod      *string
+	UserID             *string
+	StartDate          *string
+	EndDate            EndDateUpdate
+	TrialEnd           EndDateUpdate
+
+	// Tags заменяет набор тегов целиком; AddTags/RemoveTags меняют его точечно.
+	// Tags нельзя совмещать с AddTags/RemoveTags.
+	Tags       *[]string
+	AddTags    []string
+	RemoveTags []string
+
+	// Members заменяет список участников целиком; пустой список делает подписку личной.
+	Members *[]domain.SubscriptionMember
+
+	// IfMatch — ожидаемая версия подписки (If-Match); nil = без проверки
+	IfMatch *int64
+
+	// RejectOverlaps — строгий режим: изменение, после которого подписка дублирует другую, отклоняется (ErrConflict).
+	RejectOverlaps bool
+}
+
+// changesCost сообщает, может ли PATCH изменить расходы по бюджетам: цену, срок, период оплаты,
+// валюту, плательщика и участников или теги, по которым бюджеты отбирают подписки.
+func (r UpdateSubscriptionRequest) changesCost() bool {
+	return r.Price != nil || r.StartDate != nil || r.EndDate.Provided || r.TrialEnd.Provided ||
+		r.BillingPeriod != nil || r.Currency != nil || r.UserID != nil || r.Members != nil ||
+		r.Tags != nil || len(r.AddTags) > 0 || len(r.RemoveTags) > 0
 }
 
 func (s *SubscriptionService) Create(ctx context.Context, req CreateSubscriptionRequest) (int64, error) {
+	return s.create(ctx, req, nil)
+}
+
+// CreateWithBudgetCheck создаёт подписку как Create и сверяет изменение с бюджетами её участников.
+func (s *SubscriptionService) CreateWithBudgetCheck(ctx context.Context, req CreateSubscriptionRequest) (int64, BudgetCheck, error) {
+	var check BudgetCheck
+	id, err := s.create(ctx, req, &check)
+	return id, check, err
+}
+
+// create создаёт подписку; с check — заполняет его по расходам до и после создания.
+func (s *SubscriptionService) create(ctx context.Context, req CreateSubscriptionRequest, check *BudgetCheck) (int64, error) {
+	sub, err := newSubscription(req)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.applyCatalog(ctx, &sub, true); err != nil {
+		return 0, err
+	}
+
+	var baseline budgetBaseline
+	if check != nil {
+		baseline, check.Err = s.budgetBaseline(ctx, sub)
+	}
+	id, err := s.repo.Create(ctx, sub, req.RejectOverlaps)
+	if err != nil {
+		return 0, err
+	}
+	if check != nil && check.Err == nil {
+		sub.ID = id
+		check.Warnings, check.Err = s.budgetWarnings(ctx, sub, baseline)
+	}
+	return id, nil
+}
+
+// OverlapConflict — ошибка строгого режима: sub дублирует подписку other (см. domain.Subscription.Duplicates).
+// Возвращается репозиторием, который проверяет пересечения в одной транзакции с записью.
+func OverlapConflict(sub, other domain.Subscription) error {
+	o := domain.NewSubscriptionOverlap(sub, other)
+	period := "from " + domain.FormatStartDate(o.From)
+	if o.To != nil {
+		period += " to " + domain.FormatEndDate(*o.To)
+	}
+	return fmt.Errorf("%w: overlaps subscription %d to %s %s",
+		ErrConflict, other.ID, sub.ServiceName, period)
+}
+
+// Duplicates возвращает пары пересекающихся подписок одного пользователя на один сервис.
+func (s *SubscriptionService) Duplicates(ctx context.Context, f domain.DuplicateFilter) ([]domain.SubscriptionOverlap, error) {
+	name, err := s.canonicalServiceName(ctx, f.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	f.ServiceName = name
+	return s.repo.Duplicates(ctx, f)
+}
+
+// applyCatalog привязывает подписку к записи каталога по имени или алиасу и подставляет
+// каноническое имя; цена по умолчанию берётся из каталога, если не задана.
+// create — завести запись каталога для нового имени.
+func (s *SubscriptionService) applyCatalog(ctx context.Context, sub *domain.Subscription, create bool) error {
+	sub.ServiceName = domain.CleanServiceName(sub.ServiceName)
+	if sub.ServiceName == "" {
+		return fmt.Errorf("%w: service_name is required", ErrInvalidInput)
+	}
+
+	svc, err := s.catalog.Resolve(ctx, sub.ServiceName)
+	if err != nil {
+		return err
+	}
+	if svc != nil && sub.Price == 0 && svc.DefaultPrice != nil {
+		sub.Price = *svc.DefaultPrice
+	}
+	if sub.Price <= 0 {
+		return fmt.Errorf("%w: price must be > 0", ErrInvalidInput)
+	}
+
+	// новую запись заводим только для валидной подписки, чтобы не мусорить в каталоге
+	if svc == nil && create {
+		if svc, err = s.catalog.Ensure(ctx, sub.ServiceName); err != nil {
+			return err
+		}
+	}
+	if svc != nil {
+		sub.ServiceID = &svc.ID
+		sub.ServiceName = svc.Name
+	}
+	return nil
+}
+
+// canonicalServiceName заменяет имя или алиас из фильтра на каноническое имя из каталога.
+func (s *SubscriptionService) canonicalServiceName(ctx context.Context, name *string) (*string, error) {
+	if name == nil || *name == "" {
+		return name, nil
+	}
+	svc, err := s.catalog.Resolve(ctx, *name)
+	if err != nil {
+		return nil, err
+	}
+	if svc == nil {
+		return name, nil
+	}
+	return &svc.Name, nil
+}
+
+// newSubscription проверяет запрос на создание; те же правила применяются к строкам импорта.
+// Нулевая цена допустима: её может подставить каталог (см. applyCatalog).
+func newSubscription(req CreateSubscriptionRequest) (domain.Subscription, error) {
 	if req.ServiceName == "" || req.UserID == "" || req.Price < 0 || req.StartDate == "" {
-		return 0, fmt.Errorf("%w: required fields missing", ErrInvalidInput)
+		return domain.Subscription{}, fmt.Errorf("%w: required fields missing", ErrInvalidInput)
+	}
+
+	// без проверки postgres отверг бы строку уже при вставке, а memory сохранил бы её как есть
+	userID, err := domain.ParseUserID(req.UserID)
+	if err != nil {
+		return domain.Subscription{}, fmt.Errorf("%w: %v", ErrInvalidInput, err)
 	}
 
-	start, err := parseMonthYear(req.StartDate)
+	start, err := domain.ParseStartDate(req.StartDate)
 	if err != nil {
-		return 0, fmt.Errorf("%w: invalid start_date", ErrInvalidInput)
+		return domain.Subscription{}, fmt.Errorf("%w: invalid start_date", ErrInvalidInput)
+	}
+
+	period, err := domain.ParseBillingPeriod(req.BillingPeriod)
+	if err != nil {
+		return domain.Subscription{}, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	currency, err := domain.ParseCurrency(req.Currency)
+	if err != nil {
+		return domain.Subscription{}, fmt.Errorf("%w: %v", ErrInvalidInput, err)
 	}
 
 	var end *time.Time
 	if req.EndDate != nil {
-		e, err := parseMonthYear(*req.EndDate)
+		e, err := domain.ParseEndDate(*req.EndDate)
 		if err != nil {
-			return 0, fmt.Errorf("%w: invalid end_date", ErrInvalidInput)
+			return domain.Subscription{}, fmt.Errorf("%w: invalid end_date", ErrInvalidInput)
 		}
 		if e.Before(start) {
-			return 0, fmt.Errorf("%w: end_date before start_date", ErrInvalidInput)
+			return domain.Subscription{}, fmt.Errorf("%w: end_date before start_date", ErrInvalidInput)
 		}
 		end = &e
 	}
 
-	sub := domain.Subscription{
-		ServiceName: req.ServiceName,
-		Price:       req.Price,
-		UserID:      req.UserID,
-		StartDate:   start,
-		EndDate:     end,
+	var trialEnd *time.Time
+	if req.TrialEnd != nil {
+		t, err := parseMonthYear(*req.TrialEnd)
+		if err != nil {
+			return domain.Subscription{}, fmt.Errorf("%w: invalid trial_end", ErrInvalidInput)
+		}
+		trialEnd = &t
+	}
+	if err := validateTrial(start, end, trialEnd); err != nil {
+		return domain.Subscription{}, err
+	}
+
+	tags, err := domain.NormalizeTags(req.Tags)
+	if err != nil {
+		return domain.Subscription{}, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	members, err := domain.NormalizeMembers(req.Members)
+	if err != nil {
+		return domain.Subscription{}, fmt.Errorf("%w: %v", ErrInvalidInput, err)
 	}
 
-	return s.repo.Create(ctx, sub)
+	return domain.Subscription{
+		ServiceName:   req.ServiceName,
+		Price:         req.Price,
+		Currency:      currency,
+		BillingPeriod: period,
+		UserID:        userID,
+		StartDate:     start,
+		EndDate:       end,
+		TrialEnd:      trialEnd,
+		Tags:          tags,
+		Members:       members,
+	}, nil
 }
 
 func (s *SubscriptionService) GetByID(ctx context.Context, id int64) (*domain.Subscription, error) {
@@ -96,37 +296,129 @@ func (s *SubscriptionService) GetByID(ctx context.Context, id int64) (*domain.Su
 	return s.repo.GetByID(ctx, id)
 }
 
+// maxUpdateAttempts — сколько раз Update без If-Match перечитывает подписку,
+// если её успели изменить между чтением и записью.
+const maxUpdateAttempts = 3
+
 func (s *SubscriptionService) Update(ctx context.Context, id int64, req UpdateSubscriptionRequest) (*domain.Subscription, error) {
+	return s.update(ctx, id, req, nil)
+}
+
+// UpdateWithBudgetCheck изменяет подписку как Update и сверяет изменение с бюджетами её участников.
+func (s *SubscriptionService) UpdateWithBudgetCheck(ctx context.Context, id int64, req UpdateSubscriptionRequest) (*domain.Subscription, BudgetCheck, error) {
+	var check BudgetCheck
+	updated, err := s.update(ctx, id, req, &check)
+	return updated, check, err
+}
+
+// update изменяет подписку; с check — заполняет его по расходам до и после изменения.
+func (s *SubscriptionService) update(ctx context.Context, id int64, req UpdateSubscriptionRequest, check *BudgetCheck) (*domain.Subscription, error) {
 	if id <= 0 {
 		return nil, fmt.Errorf("%w: invalid id", ErrInvalidInput)
 	}
-
-	existing, err := s.repo.GetByID(ctx, id)
-	if err != nil {
-		return nil, err
+	// правка, не влияющая на расходы, не может добавить превышений: бюджеты не сверяем
+	if !req.changesCost() {
+		check = nil
 	}
-	if existing == nil {
-		return nil, ErrNotFound
+
+	for attempt := 1; ; attempt++ {
+		existing, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, ErrNotFound
+		}
+		if req.IfMatch != nil && *req.IfMatch != existing.Version {
+			return nil, ErrPreconditionFailed
+		}
+
+		price, err := applyUpdate(existing, req)
+		if err != nil {
+			return nil, err
+		}
+		// скидки не входят в existing, их проверяем отдельно, как паузы в applyUpdate
+		if req.StartDate != nil || req.EndDate.Provided {
+			if err := s.checkDiscountsInRange(ctx, *existing); err != nil {
+				return nil, err
+			}
+		}
+		if req.ServiceName != nil {
+			if err := s.applyCatalog(ctx, existing, true); err != nil {
+				return nil, err
+			}
+			if price != nil {
+				price.Price = existing.Price
+			}
+		}
+
+		var baseline budgetBaseline
+		if check != nil {
+			baseline, check.Err = s.budgetBaseline(ctx, *existing)
+		}
+
+		// repo.Update сравнивает existing.Version с текущей версией строки
+		updated, err := s.repo.Update(ctx, *existing, price, req.RejectOverlaps)
+		if errors.Is(err, ErrPreconditionFailed) && req.IfMatch == nil && attempt < maxUpdateAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if updated == nil {
+			return nil, ErrNotFound
+		}
+		if check != nil && check.Err == nil {
+			check.Warnings, check.Err = s.budgetWarnings(ctx, *updated, baseline)
+		}
+		return updated, nil
 	}
+}
 
-	// применяем PATCH
+// applyUpdate применяет PATCH к existing и возвращает изменение цены для истории (или nil).
+func applyUpdate(existing *domain.Subscription, req UpdateSubscriptionRequest) (*domain.PriceChange, error) {
 	if req.ServiceName != nil {
 		existing.ServiceName = *req.ServiceName
 	}
 	if req.Price != nil {
-		if *req.Price < 0 {
-			return nil, fmt.Errorf("%w: price must be >= 0", ErrInvalidInput)
+		// нулевая цена — только вместе с service_name: её заменит default_price каталога (см. applyCatalog)
+		if *req.Price < 0 || (*req.Price == 0 && req.ServiceName == nil) {
+			return nil, fmt.Errorf("%w: price must be > 0", ErrInvalidInput)
 		}
 		existing.Price = *req.Price
 	}
+	if req.PriceEffectiveFrom != nil && req.Price == nil {
+		return nil, fmt.Errorf("%w: price_effective_from requires price", ErrInvalidInput)
+	}
+	if req.Currency != nil {
+		if *req.Currency == "" {
+			return nil, fmt.Errorf("%w: currency empty", ErrInvalidInput)
+		}
+		currency, err := domain.ParseCurrency(*req.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		existing.Currency = currency
+	}
+	if req.BillingPeriod != nil {
+		period, err := domain.ParseBillingPeriod(*req.BillingPeriod)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		existing.BillingPeriod = period
+	}
 	if req.UserID != nil {
 		if *req.UserID == "" {
 			return nil, fmt.Errorf("%w: user_id empty", ErrInvalidInput)
 		}
-		existing.UserID = *req.UserID
+		userID, err := domain.ParseUserID(*req.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		existing.UserID = userID
 	}
 	if req.StartDate != nil {
-		start, err := parseMonthYear(*req.StartDate)
+		start, err := domain.ParseStartDate(*req.StartDate)
 		if err != nil {
 			return nil, fmt.Errorf("%w: invalid start_date", ErrInvalidInput)
 		}
@@ -137,7 +429,7 @@ func (s *SubscriptionService) Update(ctx context.Context, id int64, req UpdateSu
 		if req.EndDate.Value == nil {
 			existing.EndDate = nil
 		} else {
-			end, err := parseMonthYear(*req.EndDate.Value)
+			end, err := domain.ParseEndDate(*req.EndDate.Value)
 			if err != nil {
 				return nil, fmt.Errorf("%w: invalid end_date", ErrInvalidInput)
 			}
@@ -145,16 +437,104 @@ func (s *SubscriptionService) Update(ctx context.Context, id int64, req UpdateSu
 		}
 	}
 
+	if req.TrialEnd.Provided {
+		if req.TrialEnd.Value == nil {
+			existing.TrialEnd = nil
+		} else {
+			t, err := parseMonthYear(*req.TrialEnd.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid trial_end", ErrInvalidInput)
+			}
+			existing.TrialEnd = &t
+		}
+	}
+
+	if err := applyTagUpdate(existing, req); err != nil {
+		return nil, err
+	}
+	if req.Members != nil {
+		members, err := domain.NormalizeMembers(*req.Members)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		existing.Members = members
+	}
+
 	// финальная проверка диапазона дат
 	if existing.EndDate != nil && existing.EndDate.Before(existing.StartDate) {
 		return nil, fmt.Errorf("%w: end_date before start_date", ErrInvalidInput)
 	}
+	if err := validateTrial(existing.StartDate, existing.EndDate, existing.TrialEnd); err != nil {
+		return nil, err
+	}
+	// новые даты не должны оставить паузы за пределами срока, как при Pause
+	if req.StartDate != nil || req.EndDate.Provided {
+		for _, p := range existing.Pauses {
+			if err := checkPauseInRange(*existing, p); err != nil {
+				return nil, err
+			}
+		}
+	}
 
-	return s.repo.Update(ctx, *existing)
+	if req.Price == nil {
+		return nil, nil
+	}
+
+	// без price_effective_from цена переписывается с начала подписки, как раньше
+	price := &domain.PriceChange{
+		SubscriptionID: existing.ID,
+		EffectiveFrom:  domain.MonthStartUTC(existing.StartDate),
+		Price:          *req.Price,
+	}
+	if req.PriceEffectiveFrom != nil {
+		from, err := parseMonthYear(*req.PriceEffectiveFrom)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid price_effective_from", ErrInvalidInput)
+		}
+		if from.Before(domain.MonthStartUTC(existing.StartDate)) {
+			return nil, fmt.Errorf("%w: price_effective_from before start_date", ErrInvalidInput)
+		}
+		if existing.EndDate != nil && from.After(*existing.EndDate) {
+			return nil, fmt.Errorf("%w: price_effective_from after end_date", ErrInvalidInput)
+		}
+		price.EffectiveFrom = from
+	}
+	return price, nil
 }
 
-func (s *SubscriptionService) Delete(ctx context.Context, id int64) error {
-	deleted, err := s.repo.Delete(ctx, id)
+// PriceHistory возвращает все изменения цены подписки в порядке вступления в силу.
+func (s *SubscriptionService) PriceHistory(ctx context.Context, id int64) ([]domain.PriceChange, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: invalid id", ErrInvalidInput)
+	}
+
+	existing, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrNotFound
+	}
+
+	return s.repo.ListPrices(ctx, id)
+}
+
+// History возвращает журнал изменений подписки. Записи удалённых подписок тоже доступны.
+func (s *SubscriptionService) History(ctx context.Context, f domain.EventFilter) ([]domain.SubscriptionEvent, error) {
+	if f.SubscriptionID <= 0 {
+		return nil, fmt.Errorf("%w: invalid id", ErrInvalidInput)
+	}
+	return s.repo.ListEvents(ctx, f)
+}
+
+// Delete удаляет подписку; ifMatch != nil — удалить, только если версия совпадает.
+func (s *SubscriptionService) Delete(ctx context.Context, id int64, ifMatch *int64) error {
+	var version int64
+	if ifMatch != nil {
+		version = *ifMatch
+	}
+
+	deleted, err := s.repo.Delete(ctx, id, version)
 	if err != nil {
 		return err
 	}
@@ -164,15 +544,304 @@ func (s *SubscriptionService) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
+func (s *SubscriptionService) Restore(ctx context.Context, id int64) (*domain.Subscription, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: invalid id", ErrInvalidInput)
+	}
+
+	restored, err := s.repo.Restore(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if restored == nil {
+		return nil, ErrNotFound
+	}
+	return restored, nil
+}
+
+// Pause приостанавливает оплату с месяца from ("MM-YYYY") по until включительно; until == nil — до Resume.
+// Пауза должна лежать внутри срока подписки.
+func (s *SubscriptionService) Pause(ctx context.Context, id int64, from string, until *string) (*domain.Subscription, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: invalid id", ErrInvalidInput)
+	}
+
+	p := domain.Pause{}
+	var err error
+	if p.From, err = parseMonthYear(from); err != nil {
+		return nil, fmt.Errorf("%w: from must be MM-YYYY", ErrInvalidInput)
+	}
+	if until != nil {
+		u, err := parseMonthYear(*until)
+		if err != nil {
+			return nil, fmt.Errorf("%w: until must be MM-YYYY", ErrInvalidInput)
+		}
+		if u.Before(p.From) {
+			return nil, fmt.Errorf("%w: until before from", ErrInvalidDateRange)
+		}
+		p.Until = &u
+	}
+
+	existing, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrNotFound
+	}
+	if err := checkPauseInRange(*existing, p); err != nil {
+		return nil, err
+	}
+
+	paused, err := s.repo.Pause(ctx, id, p)
+	if err != nil {
+		return nil, err
+	}
+	if paused == nil {
+		return nil, ErrNotFound
+	}
+	return paused, nil
+}
+
+// checkPauseInRange проверяет, что пауза лежит внутри срока подписки.
+func checkPauseInRange(sub domain.Subscription, p domain.Pause) error {
+	if p.From.Before(domain.MonthStartUTC(sub.StartDate)) {
+		return fmt.Errorf("%w: pause starts before start_date", ErrInvalidInput)
+	}
+	if sub.EndDate != nil && (p.Until == nil || p.Until.After(*sub.EndDate)) {
+		return fmt.Errorf("%w: pause must end by end_date", ErrInvalidInput)
+	}
+	return nil
+}
+
+// Resume возобновляет оплату с месяца at ("MM-YYYY"), прерывая паузу, которая действует в этом месяце.
+// Если at — первый месяц паузы, пауза отменяется целиком.
+func (s *SubscriptionService) Resume(ctx context.Context, id int64, at string) (*domain.Subscription, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("%w: invalid id", ErrInvalidInput)
+	}
+	m, err := parseMonthYear(at)
+	if err != nil {
+		return nil, fmt.Errorf("%w: at must be MM-YYYY", ErrInvalidInput)
+	}
+
+	resumed, err := s.repo.Resume(ctx, id, m)
+	if err != nil {
+		return nil, err
+	}
+	if resumed == nil {
+		return nil, ErrNotFound
+	}
+	return resumed, nil
+}
+
+// PurgeDeleted физически удаляет подписки, которые лежат удалёнными дольше retention.
+func (s *SubscriptionService) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	if retention <= 0 {
+		return 0, fmt.Errorf("%w: retention must be > 0", ErrInvalidInput)
+	}
+	return s.repo.Purge(ctx, s.now().Add(-retention))
+}
+
+// List — постраничный список в режиме offset (для обратной совместимости).
+// Слишком большой или нулевой limit молча заменяется на DefaultListLimit, как раньше.
 func (s *SubscriptionService) List(ctx context.Context, f domain.ListFilter) ([]domain.Subscription, error) {
-	// тут можно добавить валидацию фильтров, но сейчас держим просто
+	if err := s.prepareListFilter(ctx, &f); err != nil {
+		return nil, err
+	}
+	if f.Limit <= 0 || f.Limit > domain.MaxListLimit {
+		f.Limit = domain.DefaultListLimit
+	}
+	f.After = nil
 	return s.repo.List(ctx, f)
 }
 
-func (s *SubscriptionService) TotalCost(ctx context.Context, f domain.TotalFilter) (int64, error) {
+// ListPage — постраничный список по курсору (keyset по ключу сортировки и id).
+// withTotal добавляет общее число подписок под фильтром.
+func (s *SubscriptionService) ListPage(ctx context.Context, f domain.ListFilter, withTotal bool) (*domain.SubscriptionPage, error) {
+	if err := s.prepareListFilter(ctx, &f); err != nil {
+		return nil, err
+	}
+	if f.Limit <= 0 {
+		f.Limit = domain.DefaultListLimit
+	}
+	if f.Limit > domain.MaxListLimit {
+		return nil, fmt.Errorf("%w: limit must be <= %d", ErrInvalidInput, domain.MaxListLimit)
+	}
+	if f.After != nil {
+		// курсор, выданный для другой сортировки, дал бы пропуски и повторы
+		cursorSort := f.After.Sort
+		if cursorSort == "" {
+			cursorSort = string(domain.SortByID)
+		}
+		if cursorSort != f.Sort.String() {
+			return nil, fmt.Errorf("%w: cursor does not match sort", ErrInvalidInput)
+		}
+		if _, err := f.Sort.ParseKey(f.After.Key); err != nil {
+			return nil, fmt.Errorf("%w: invalid cursor", ErrInvalidInput)
+		}
+	}
+	limit := f.Limit
+
+	// лишняя строка показывает, есть ли следующая страница
+	f.Limit = limit + 1
+	f.Offset = 0
+	items, err := s.repo.List(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.SubscriptionPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		last := page.Items[limit-1]
+		next := domain.EncodeCursor(domain.ListCursor{Sort: f.Sort.String(), Key: f.Sort.Key(last), ID: last.ID})
+		page.NextCursor = &next
+	}
+
+	if withTotal {
+		countFilter := f
+		countFilter.After = nil
+		total, err := s.repo.Count(ctx, countFilter)
+		if err != nil {
+			return nil, err
+		}
+		page.TotalCount = &total
+	}
+	return page, nil
+}
+
+// Export выгружает все подписки под фильтром, без ограничения List на 200 строк.
+func (s *SubscriptionService) Export(ctx context.Context, f domain.ListFilter, fn func(domain.Subscription) error) error {
+	if err := s.prepareListFilter(ctx, &f); err != nil {
+		return err
+	}
+	f.After = nil
+	return s.repo.Export(ctx, f, fn)
+}
+
+// prepareListFilter проверяет фильтр, нормализует теги и приводит service_name к имени из каталога.
+func (s *SubscriptionService) prepareListFilter(ctx context.Context, f *domain.ListFilter) error {
+	if f.PriceMin != nil && f.PriceMax != nil && *f.PriceMin > *f.PriceMax {
+		return fmt.Errorf("%w: price_min greater than price_max", ErrInvalidInput)
+	}
+	if f.Status != "" && f.StatusAt.IsZero() {
+		return fmt.Errorf("%w: status requires a month", ErrInvalidInput)
+	}
+	tags, err := domain.NormalizeTags(f.Tags)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	f.Tags = tags
+
+	name, err := s.canonicalServiceName(ctx, f.ServiceName)
+	if err != nil {
+		return err
+	}
+	f.ServiceName = name
+	return nil
+}
+
+// TotalCost считает стоимость в f.Currency до и после скидок; каждое месячное списание переводится по курсу этого месяца.
+func (s *SubscriptionService) TotalCost(ctx context.Context, f domain.TotalFilter) (domain.CostTotal, error) {
+	if err := s.prepareTotalFilter(ctx, &f); err != nil {
+		return domain.CostTotal{}, err
+	}
 	return s.repo.TotalCost(ctx, f)
 }
 
+// TotalBreakdown возвращает стоимость по каждому месяцу периода, включая месяцы без списаний.
+func (s *SubscriptionService) TotalBreakdown(ctx context.Context, f domain.TotalFilter) ([]domain.MonthlyCost, error) {
+	if err := s.prepareTotalFilter(ctx, &f); err != nil {
+		return nil, err
+	}
+	return s.repo.TotalBreakdown(ctx, f)
+}
+
+// Forecast прогнозирует стоимость на months месяцев вперёд, начиная с текущего.
+// Считается так же, как TotalBreakdown: учитываются end_date, будущие цены из истории, пробные периоды,
+// паузы и скидки; валюты переводятся по последним загруженным курсам. f.From и f.To игнорируются.
+func (s *SubscriptionService) Forecast(ctx context.Context, f domain.TotalFilter, months int) ([]domain.MonthlyCost, error) {
+	if months < 1 || months > domain.MaxForecastMonths {
+		return nil, fmt.Errorf("%w: months must be between 1 and %d", ErrInvalidInput, domain.MaxForecastMonths)
+	}
+	f.From = domain.MonthStartUTC(s.now().UTC())
+	f.To = f.From.AddDate(0, months-1, 0)
+	f.LatestRates = true
+	return s.TotalBreakdown(ctx, f)
+}
+
+// TotalCostGrouped считает стоимость отдельно для каждой группы из f.GroupBy.
+func (s *SubscriptionService) TotalCostGrouped(ctx context.Context, f domain.TotalFilter) ([]domain.GroupTotal, error) {
+	if len(f.GroupBy) == 0 {
+		return nil, fmt.Errorf("%w: group_by is empty", ErrInvalidInput)
+	}
+	if err := s.prepareTotalFilter(ctx, &f); err != nil {
+		return nil, err
+	}
+	return s.repo.TotalCostGrouped(ctx, f)
+}
+
+// prepareTotalFilter проверяет период, нормализует теги и приводит service_name к имени из каталога.
+func (s *SubscriptionService) prepareTotalFilter(ctx context.Context, f *domain.TotalFilter) error {
+	if err := f.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDateRange, err)
+	}
+	tags, err := domain.NormalizeTags(f.Tags)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	f.Tags = tags
+
+	name, err := s.canonicalServiceName(ctx, f.ServiceName)
+	if err != nil {
+		return err
+	}
+	f.ServiceName = name
+	return nil
+}
+
+// validateTrial проверяет, что месяц trial_end лежит между start_date и end_date.
+func validateTrial(start time.Time, end, trialEnd *time.Time) error {
+	if trialEnd == nil {
+		return nil
+	}
+	if trialEnd.Before(domain.MonthStartUTC(start)) {
+		return fmt.Errorf("%w: trial_end before start_date", ErrInvalidInput)
+	}
+	if end != nil && trialEnd.After(*end) {
+		return fmt.Errorf("%w: trial_end after end_date", ErrInvalidInput)
+	}
+	return nil
+}
+
+// applyTagUpdate применяет к existing замену или добавление/удаление тегов из PATCH.
+func applyTagUpdate(existing *domain.Subscription, req UpdateSubscriptionRequest) error {
+	if req.Tags != nil && (len(req.AddTags) > 0 || len(req.RemoveTags) > 0) {
+		return fmt.Errorf("%w: tags cannot be combined with add_tags or remove_tags", ErrInvalidInput)
+	}
+	if req.Tags == nil && len(req.AddTags) == 0 && len(req.RemoveTags) == 0 {
+		return nil
+	}
+
+	tags := existing.Tags
+	if req.Tags != nil {
+		tags = *req.Tags
+	}
+	remove, err := domain.NormalizeTags(req.RemoveTags)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	tags = slices.DeleteFunc(append(slices.Clone(tags), req.AddTags...), func(t string) bool {
+		return slices.Contains(remove, domain.NormalizeTag(t))
+	})
+
+	if existing.Tags, err = domain.NormalizeTags(tags); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return nil
+}
+
 func parseMonthYear(v string) (time.Time, error) {
 	// формат "MM-YYYY"
 	t, err := time.Parse("01-2006", v)
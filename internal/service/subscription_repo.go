@@ -3,14 +3,63 @@ package service
 import (
 	"context"
 	"subscription_service/internal/domain"
+	"time"
 )
 
+// SubscriptionRepository хранит подписки. Create, Update и Delete
+// записывают событие в журнал аудита атомарно с самим изменением.
 type SubscriptionRepository interface {
-	Create(ctx context.Context, s domain.Subscription) (int64, error)
+	// Create сохраняет новую подписку. rejectOverlaps — строгий режим: если s дублирует неудалённую
+	// подписку (см. Overlapping), ничего не сохраняется и возвращается ошибка OverlapConflict.
+	// Проверка и запись атомарны относительно других строгих записей того же пользователя и сервиса.
+	Create(ctx context.Context, s domain.Subscription, rejectOverlaps bool) (int64, error)
+	// CreateBatch создаёт все подписки в одной транзакции и возвращает их id в том же порядке.
+	// Подписки без ServiceID привязываются к каталогу: новые имена заводятся в нём в той же транзакции
+	// (как CatalogRepository.Ensure), так что при ошибке в каталоге ничего не остаётся.
+	CreateBatch(ctx context.Context, subs []domain.Subscription) ([]int64, error)
 	GetByID(ctx context.Context, id int64) (*domain.Subscription, error)
-	Update(ctx context.Context, s domain.Subscription) (*domain.Subscription, error)
-	Delete(ctx context.Context, id int64) (bool, error)
+	// Update сохраняет подписку, если её версия всё ещё равна s.Version, и увеличивает версию;
+	// иначе ErrPreconditionFailed. price != nil добавляет изменение цены в историю.
+	// rejectOverlaps — как в Create.
+	Update(ctx context.Context, s domain.Subscription, price *domain.PriceChange, rejectOverlaps bool) (*domain.Subscription, error)
+	// Delete — мягкое удаление: подписка пропадает из GetByID, List и TotalCost, но её можно восстановить.
+	// version != 0 — удалить только при совпадении версии, иначе ErrPreconditionFailed.
+	Delete(ctx context.Context, id int64, version int64) (bool, error)
+	// Restore снимает пометку удаления; nil, если удалённой подписки с таким id нет.
+	Restore(ctx context.Context, id int64) (*domain.Subscription, error)
+	// Pause добавляет паузу, Resume укорачивает паузу, действующую в месяце at, так что с at оплата возобновляется.
+	// Оба увеличивают версию и пишут событие; nil, если подписки нет.
+	// Пересечение с другой паузой — ErrAlreadyPaused, отсутствие паузы в at — ErrNotPaused.
+	Pause(ctx context.Context, id int64, p domain.Pause) (*domain.Subscription, error)
+	Resume(ctx context.Context, id int64, at time.Time) (*domain.Subscription, error)
+	// Purge физически удаляет подписки, удалённые раньше olderThan, и возвращает их количество.
+	Purge(ctx context.Context, olderThan time.Time) (int64, error)
 
+	ListPrices(ctx context.Context, id int64) ([]domain.PriceChange, error)
+	// AddDiscount сохраняет скидку и заполняет её ID и CreatedAt.
+	AddDiscount(ctx context.Context, d domain.Discount) (domain.Discount, error)
+	// ListDiscounts возвращает скидки подписки в порядке начала действия.
+	ListDiscounts(ctx context.Context, id int64) ([]domain.Discount, error)
+	// DeleteDiscount удаляет скидку подписки; false, если такой нет.
+	DeleteDiscount(ctx context.Context, id, discountID int64) (bool, error)
+	// ListEvents возвращает журнал изменений подписки, новые записи первыми.
+	ListEvents(ctx context.Context, f domain.EventFilter) ([]domain.SubscriptionEvent, error)
+
+	// List возвращает не больше f.Limit строк; f.After — keyset-режим, Offset игнорируется.
 	List(ctx context.Context, f domain.ListFilter) ([]domain.Subscription, error)
-	TotalCost(ctx context.Context, f domain.TotalFilter) (int64, error)
+	// Export вызывает fn для каждой подписки под фильтром без ограничения на число строк;
+	// пагинация в f игнорируется. Ошибка fn прерывает выгрузку.
+	Export(ctx context.Context, f domain.ListFilter, fn func(domain.Subscription) error) error
+	// Count — число подписок под фильтром без учёта пагинации.
+	Count(ctx context.Context, f domain.ListFilter) (int64, error)
+	// Duplicates возвращает пары неудалённых подписок одного плательщика на один сервис с пересекающимися датами
+	// (см. domain.Subscription.Duplicates), по user_id, service_name и id.
+	Duplicates(ctx context.Context, f domain.DuplicateFilter) ([]domain.SubscriptionOverlap, error)
+	// Overlapping возвращает неудалённые подписки, которые s дублирует, по дате начала.
+	// s может быть ещё не сохранена (ID = 0).
+	Overlapping(ctx context.Context, s domain.Subscription) ([]domain.Subscription, error)
+	// TotalCost — стоимость до и после скидок; TotalBreakdown и TotalCostGrouped считают её после скидок.
+	TotalCost(ctx context.Context, f domain.TotalFilter) (domain.CostTotal, error)
+	TotalBreakdown(ctx context.Context, f domain.TotalFilter) ([]domain.MonthlyCost, error)
+	TotalCostGrouped(ctx context.Context, f domain.TotalFilter) ([]domain.GroupTotal, error)
 }
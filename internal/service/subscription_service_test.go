@@ -3,28 +3,55 @@ package service
 import (
 	"context"
 	"errors"
+	"slices"
+	"strings"
 	"subscription_service/internal/domain"
 	"testing"
+	"time"
 )
 
 // ---- repo mock ----
 
 type repoMock struct {
 	createFn    func(ctx context.Context, s domain.Subscription) (int64, error)
+	batchFn     func(ctx context.Context, subs []domain.Subscription) ([]int64, error)
 	getByIDFn   func(ctx context.Context, id int64) (*domain.Subscription, error)
-	updateFn    func(ctx context.Context, s domain.Subscription) (*domain.Subscription, error)
-	deleteFn    func(ctx context.Context, id int64) (bool, error)
+	updateFn    func(ctx context.Context, s domain.Subscription, price *domain.PriceChange) (*domain.Subscription, error)
+	deleteFn    func(ctx context.Context, id int64, version int64) (bool, error)
+	restoreFn   func(ctx context.Context, id int64) (*domain.Subscription, error)
+	pauseFn     func(ctx context.Context, id int64, p domain.Pause) (*domain.Subscription, error)
+	resumeFn    func(ctx context.Context, id int64, at time.Time) (*domain.Subscription, error)
+	purgeFn     func(ctx context.Context, olderThan time.Time) (int64, error)
+	pricesFn    func(ctx context.Context, id int64) ([]domain.PriceChange, error)
+	discountFn  func(ctx context.Context, d domain.Discount) (domain.Discount, error)
+	discountsFn func(ctx context.Context, id int64) ([]domain.Discount, error)
+	eventsFn    func(ctx context.Context, f domain.EventFilter) ([]domain.SubscriptionEvent, error)
 	listFn      func(ctx context.Context, f domain.ListFilter) ([]domain.Subscription, error)
-	totalCostFn func(ctx context.Context, f domain.TotalFilter) (int64, error)
+	countFn     func(ctx context.Context, f domain.ListFilter) (int64, error)
+	exportFn    func(ctx context.Context, f domain.ListFilter, fn func(domain.Subscription) error) error
+	totalCostFn func(ctx context.Context, f domain.TotalFilter) (domain.CostTotal, error)
+	breakdownFn func(ctx context.Context, f domain.TotalFilter) ([]domain.MonthlyCost, error)
+	groupedFn   func(ctx context.Context, f domain.TotalFilter) ([]domain.GroupTotal, error)
+	overlapFn   func(ctx context.Context, s domain.Subscription) ([]domain.Subscription, error)
+
+	rejectOverlaps bool // последний rejectOverlaps, переданный в Create или Update
 }
 
-func (m *repoMock) Create(ctx context.Context, s domain.Subscription) (int64, error) {
+func (m *repoMock) Create(ctx context.Context, s domain.Subscription, rejectOverlaps bool) (int64, error) {
 	if m.createFn == nil {
 		panic("createFn is nil")
 	}
+	m.rejectOverlaps = rejectOverlaps
 	return m.createFn(ctx, s)
 }
 
+func (m *repoMock) CreateBatch(ctx context.Context, subs []domain.Subscription) ([]int64, error) {
+	if m.batchFn == nil {
+		panic("batchFn is nil")
+	}
+	return m.batchFn(ctx, subs)
+}
+
 func (m *repoMock) GetByID(ctx context.Context, id int64) (*domain.Subscription, error) {
 	if m.getByIDFn == nil {
 		panic("getByIDFn is nil")
@@ -32,18 +59,79 @@ func (m *repoMock) GetByID(ctx context.Context, id int64) (*domain.Subscription,
 	return m.getByIDFn(ctx, id)
 }
 
-func (m *repoMock) Update(ctx context.Context, s domain.Subscription) (*domain.Subscription, error) {
+func (m *repoMock) Update(ctx context.Context, s domain.Subscription, price *domain.PriceChange, rejectOverlaps bool) (*domain.Subscription, error) {
 	if m.updateFn == nil {
 		panic("updateFn is nil")
 	}
-	return m.updateFn(ctx, s)
+	m.rejectOverlaps = rejectOverlaps
+	return m.updateFn(ctx, s, price)
 }
 
-func (m *repoMock) Delete(ctx context.Context, id int64) (bool, error) {
+func (m *repoMock) Delete(ctx context.Context, id int64, version int64) (bool, error) {
 	if m.deleteFn == nil {
 		panic("deleteFn is nil")
 	}
-	return m.deleteFn(ctx, id)
+	return m.deleteFn(ctx, id, version)
+}
+
+func (m *repoMock) Restore(ctx context.Context, id int64) (*domain.Subscription, error) {
+	if m.restoreFn == nil {
+		panic("restoreFn is nil")
+	}
+	return m.restoreFn(ctx, id)
+}
+
+func (m *repoMock) Pause(ctx context.Context, id int64, p domain.Pause) (*domain.Subscription, error) {
+	if m.pauseFn == nil {
+		panic("pauseFn is nil")
+	}
+	return m.pauseFn(ctx, id, p)
+}
+
+func (m *repoMock) Resume(ctx context.Context, id int64, at time.Time) (*domain.Subscription, error) {
+	if m.resumeFn == nil {
+		panic("resumeFn is nil")
+	}
+	return m.resumeFn(ctx, id, at)
+}
+
+func (m *repoMock) Purge(ctx context.Context, olderThan time.Time) (int64, error) {
+	if m.purgeFn == nil {
+		panic("purgeFn is nil")
+	}
+	return m.purgeFn(ctx, olderThan)
+}
+
+func (m *repoMock) ListPrices(ctx context.Context, id int64) ([]domain.PriceChange, error) {
+	if m.pricesFn == nil {
+		panic("pricesFn is nil")
+	}
+	return m.pricesFn(ctx, id)
+}
+
+func (m *repoMock) AddDiscount(ctx context.Context, d domain.Discount) (domain.Discount, error) {
+	if m.discountFn == nil {
+		panic("discountFn is nil")
+	}
+	return m.discountFn(ctx, d)
+}
+
+func (m *repoMock) ListDiscounts(ctx context.Context, id int64) ([]domain.Discount, error) {
+	if m.discountsFn == nil {
+		panic("discountsFn is nil")
+	}
+	return m.discountsFn(ctx, id)
+}
+
+func (m *repoMock) DeleteDiscount(ctx context.Context, id, discountID int64) (bool, error) {
+	panic("DeleteDiscount is not used in tests")
+}
+
+func (m *repoMock) ListEvents(ctx context.Context, f domain.EventFilter) ([]domain.SubscriptionEvent, error) {
+	if m.eventsFn == nil {
+		panic("eventsFn is nil")
+	}
+	return m.eventsFn(ctx, f)
 }
 
 func (m *repoMock) List(ctx context.Context, f domain.ListFilter) ([]domain.Subscription, error) {
@@ -53,13 +141,52 @@ func (m *repoMock) List(ctx context.Context, f domain.ListFilter) ([]domain.Subs
 	return m.listFn(ctx, f)
 }
 
-func (m *repoMock) TotalCost(ctx context.Context, f domain.TotalFilter) (int64, error) {
+func (m *repoMock) Export(ctx context.Context, f domain.ListFilter, fn func(domain.Subscription) error) error {
+	if m.exportFn == nil {
+		panic("exportFn is nil")
+	}
+	return m.exportFn(ctx, f, fn)
+}
+
+func (m *repoMock) Count(ctx context.Context, f domain.ListFilter) (int64, error) {
+	if m.countFn == nil {
+		panic("countFn is nil")
+	}
+	return m.countFn(ctx, f)
+}
+
+func (m *repoMock) TotalCost(ctx context.Context, f domain.TotalFilter) (domain.CostTotal, error) {
 	if m.totalCostFn == nil {
 		panic("totalCostFn is nil")
 	}
 	return m.totalCostFn(ctx, f)
 }
 
+func (m *repoMock) TotalBreakdown(ctx context.Context, f domain.TotalFilter) ([]domain.MonthlyCost, error) {
+	if m.breakdownFn == nil {
+		panic("breakdownFn is nil")
+	}
+	return m.breakdownFn(ctx, f)
+}
+
+func (m *repoMock) TotalCostGrouped(ctx context.Context, f domain.TotalFilter) ([]domain.GroupTotal, error) {
+	if m.groupedFn == nil {
+		panic("groupedFn is nil")
+	}
+	return m.groupedFn(ctx, f)
+}
+
+func (m *repoMock) Duplicates(ctx context.Context, f domain.DuplicateFilter) ([]domain.SubscriptionOverlap, error) {
+	panic("Duplicates is not used in tests")
+}
+
+func (m *repoMock) Overlapping(ctx context.Context, s domain.Subscription) ([]domain.Subscription, error) {
+	if m.overlapFn == nil {
+		panic("overlapFn is nil")
+	}
+	return m.overlapFn(ctx, s)
+}
+
 var _ SubscriptionRepository = (*repoMock)(nil)
 
 // ---- tests ----
@@ -72,7 +199,7 @@ func TestCreate_InvalidDateFormat_ReturnsErrInvalidInput(t *testing.T) {
 		},
 	}
 
-	svc := NewSubscriptionService(repo)
+	svc := NewSubscriptionService(repo, newCatalogMock(), nil)
 
 	_, err := svc.Create(context.Background(), CreateSubscriptionRequest{
 		ServiceName: "Netflix",
@@ -90,15 +217,69 @@ func TestCreate_InvalidDateFormat_ReturnsErrInvalidInput(t *testing.T) {
 	}
 }
 
+func TestCreate_TrialEndOutsideRange_ReturnsErrInvalidInput(t *testing.T) {
+	svc := NewSubscriptionService(&repoMock{}, newCatalogMock(), nil)
+
+	for _, tc := range []struct{ trialEnd, endDate string }{
+		{trialEnd: "06-2025"},
+		{trialEnd: "10-2025", endDate: "09-2025"},
+	} {
+		req := CreateSubscriptionRequest{
+			ServiceName: "Netflix",
+			Price:       400,
+			UserID:      "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+			StartDate:   "07-2025",
+			TrialEnd:    &tc.trialEnd,
+		}
+		if tc.endDate != "" {
+			req.EndDate = &tc.endDate
+		}
+		if _, err := svc.Create(context.Background(), req); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("trial_end %s: expected ErrInvalidInput, got %v", tc.trialEnd, err)
+		}
+	}
+}
+
+func TestCreate_DayPrecisionDates(t *testing.T) {
+	var got domain.Subscription
+	repo := &repoMock{
+		createFn: func(ctx context.Context, s domain.Subscription) (int64, error) {
+			got = s
+			return 1, nil
+		},
+	}
+	svc := NewSubscriptionService(repo, newCatalogMock(), nil)
+
+	end := "03-2025"
+	_, err := svc.Create(context.Background(), CreateSubscriptionRequest{
+		ServiceName: "Netflix",
+		Price:       400,
+		UserID:      "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		StartDate:   "2025-01-20",
+		EndDate:     &end,
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if !got.StartDate.Equal(time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)) || !got.EndDate.Equal(time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected 2025-01-20..2025-03-31, got %v..%v", got.StartDate, got.EndDate)
+	}
+
+	dto := domain.ToDTO(got)
+	if dto.StartDate != "2025-01-20" || dto.EndDate == nil || *dto.EndDate != "03-2025" {
+		t.Fatalf("expected start 2025-01-20 and end 03-2025, got %s, %v", dto.StartDate, dto.EndDate)
+	}
+}
+
 func TestDelete_NotFound_ReturnsErrNotFound(t *testing.T) {
 	repo := &repoMock{
-		deleteFn: func(ctx context.Context, id int64) (bool, error) {
+		deleteFn: func(ctx context.Context, id int64, version int64) (bool, error) {
 			return false, nil // not deleted => not found
 		},
 	}
-	svc := NewSubscriptionService(repo)
+	svc := NewSubscriptionService(repo, newCatalogMock(), nil)
 
-	err := svc.Delete(context.Background(), 999)
+	err := svc.Delete(context.Background(), 999, nil)
 	if err == nil {
 		t.Fatalf("expected error, got nil")
 	}
@@ -109,28 +290,94 @@ func TestDelete_NotFound_ReturnsErrNotFound(t *testing.T) {
 
 func TestDelete_Deleted_OK(t *testing.T) {
 	repo := &repoMock{
-		deleteFn: func(ctx context.Context, id int64) (bool, error) {
+		deleteFn: func(ctx context.Context, id int64, version int64) (bool, error) {
 			return true, nil
 		},
 	}
-	svc := NewSubscriptionService(repo)
+	svc := NewSubscriptionService(repo, newCatalogMock(), nil)
 
-	err := svc.Delete(context.Background(), 1)
+	err := svc.Delete(context.Background(), 1, nil)
 	if err != nil {
 		t.Fatalf("expected nil, got %v", err)
 	}
 }
 
+func TestPause_OutsideSubscriptionRange_ReturnsErrInvalidInput(t *testing.T) {
+	end := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	repo := &repoMock{
+		getByIDFn: func(ctx context.Context, id int64) (*domain.Subscription, error) {
+			return &domain.Subscription{ID: id, StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), EndDate: &end}, nil
+		},
+	}
+	svc := NewSubscriptionService(repo, newCatalogMock(), nil)
+	feb, mar, jul := "02-2025", "03-2025", "07-2025"
+
+	cases := []struct {
+		name  string
+		from  string
+		until *string
+	}{
+		{"before start", "12-2024", &feb},
+		{"after end", "03-2025", &jul},
+		{"open-ended with end_date", "03-2025", nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := svc.Pause(context.Background(), 1, tc.from, tc.until)
+			if !errors.Is(err, ErrInvalidInput) {
+				t.Fatalf("expected ErrInvalidInput, got %v", err)
+			}
+		})
+	}
+
+	_, err := svc.Pause(context.Background(), 1, "04-2025", &mar)
+	if !errors.Is(err, ErrInvalidDateRange) {
+		t.Fatalf("until before from: expected ErrInvalidDateRange, got %v", err)
+	}
+}
+
+func TestAddDiscount_MonthsSetsUntil(t *testing.T) {
+	var got domain.Discount
+	repo := &repoMock{
+		getByIDFn: func(ctx context.Context, id int64) (*domain.Subscription, error) {
+			return &domain.Subscription{ID: id, StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}, nil
+		},
+		discountFn: func(ctx context.Context, d domain.Discount) (domain.Discount, error) {
+			got = d
+			return d, nil
+		},
+	}
+	svc := NewSubscriptionService(repo, newCatalogMock(), nil)
+
+	months := 3
+	if _, err := svc.AddDiscount(context.Background(), 1, AddDiscountRequest{Kind: "percent", Value: 50, From: "11-2025", Months: &months}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if got.SubscriptionID != 1 || got.Until == nil || !got.Until.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected discount through 01-2026, got %+v", got)
+	}
+
+	for _, req := range []AddDiscountRequest{
+		{Kind: "percent", Value: 150, From: "02-2025"},
+		{Kind: "coupon", Value: 10, From: "02-2025"},
+		{Kind: "fixed", Value: 100, From: "12-2024"},
+	} {
+		if _, err := svc.AddDiscount(context.Background(), 1, req); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("%+v: expected ErrInvalidInput, got %v", req, err)
+		}
+	}
+}
+
 func TestTotalCost_RepoError_Propagates(t *testing.T) {
 	wantErr := errors.New("db down")
 
 	repo := &repoMock{
-		totalCostFn: func(ctx context.Context, f domain.TotalFilter) (int64, error) {
-			return 0, wantErr
+		totalCostFn: func(ctx context.Context, f domain.TotalFilter) (domain.CostTotal, error) {
+			return domain.CostTotal{}, wantErr
 		},
 	}
 
-	svc := NewSubscriptionService(repo)
+	svc := NewSubscriptionService(repo, newCatalogMock(), nil)
 
 	from, _ := domain.ParseMonthYear("07-2025")
 	to, _ := domain.ParseMonthYear("10-2025")
@@ -149,3 +396,769 @@ func TestTotalCost_RepoError_Propagates(t *testing.T) {
 		t.Fatalf("expected %v, got %v", wantErr, err)
 	}
 }
+
+func TestForecast_StartsAtCurrentMonth(t *testing.T) {
+	var got domain.TotalFilter
+	repo := &repoMock{
+		breakdownFn: func(ctx context.Context, f domain.TotalFilter) ([]domain.MonthlyCost, error) {
+			got = f
+			return nil, nil
+		},
+	}
+
+	svc := NewSubscriptionService(repo, newCatalogMock(), nil)
+	svc.now = func() time.Time { return time.Date(2025, 11, 20, 15, 0, 0, 0, time.UTC) }
+
+	if _, err := svc.Forecast(context.Background(), domain.TotalFilter{}, 3); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if domain.FormatMonthYear(got.From) != "11-2025" || domain.FormatMonthYear(got.To) != "01-2026" {
+		t.Fatalf("expected 11-2025..01-2026, got %s..%s", domain.FormatMonthYear(got.From), domain.FormatMonthYear(got.To))
+	}
+	if !got.LatestRates {
+		t.Fatalf("expected LatestRates")
+	}
+
+	for _, months := range []int{0, domain.MaxForecastMonths + 1} {
+		if _, err := svc.Forecast(context.Background(), domain.TotalFilter{}, months); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("months=%d: expected ErrInvalidInput, got %v", months, err)
+		}
+	}
+}
+
+func TestUpdate_PriceEffectiveFrom_PassesPriceChange(t *testing.T) {
+	start, _ := domain.ParseMonthYear("01-2025")
+	existing := domain.Subscription{ID: 7, ServiceName: "Netflix", Price: 400, UserID: "60610fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: start}
+
+	var gotPrice *domain.PriceChange
+	repo := &repoMock{
+		getByIDFn: func(ctx context.Context, id int64) (*domain.Subscription, error) {
+			s := existing
+			return &s, nil
+		},
+		updateFn: func(ctx context.Context, s domain.Subscription, price *domain.PriceChange) (*domain.Subscription, error) {
+			gotPrice = price
+			return &s, nil
+		},
+	}
+	svc := NewSubscriptionService(repo, newCatalogMock(), nil)
+
+	newPrice := int64(500)
+	from := "06-2025"
+	updated, err := svc.Update(context.Background(), 7, UpdateSubscriptionRequest{
+		Price:              &newPrice,
+		PriceEffectiveFrom: &from,
+		EndDate:            EndDateNotProvided(),
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if updated.Price != 500 {
+		t.Fatalf("expected current price 500, got %d", updated.Price)
+	}
+	if gotPrice == nil || gotPrice.Price != 500 || domain.FormatMonthYear(gotPrice.EffectiveFrom) != "06-2025" {
+		t.Fatalf("expected price change 500 from 06-2025, got %+v", gotPrice)
+	}
+}
+
+func TestUpdate_ZeroPriceOnlyWithCatalogDefault(t *testing.T) {
+	start, _ := domain.ParseMonthYear("01-2025")
+	existing := domain.Subscription{ID: 7, ServiceName: "Netflix", Price: 400, UserID: "60610fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: start}
+
+	var gotPrice *domain.PriceChange
+	repo := &repoMock{
+		getByIDFn: func(ctx context.Context, id int64) (*domain.Subscription, error) {
+			s := existing
+			return &s, nil
+		},
+		updateFn: func(ctx context.Context, s domain.Subscription, price *domain.PriceChange) (*domain.Subscription, error) {
+			gotPrice = price
+			return &s, nil
+		},
+	}
+	defaultPrice := int64(299)
+	catalog := newCatalogMock(domain.Service{ID: 5, Name: "Spotify", DefaultPrice: &defaultPrice})
+	svc := NewSubscriptionService(repo, catalog, nil)
+
+	zero := int64(0)
+	_, err := svc.Update(context.Background(), 7, UpdateSubscriptionRequest{Price: &zero, EndDate: EndDateNotProvided()})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput for price 0, got %v", err)
+	}
+
+	name := "spotify"
+	updated, err := svc.Update(context.Background(), 7, UpdateSubscriptionRequest{ServiceName: &name, Price: &zero, EndDate: EndDateNotProvided()})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if updated.Price != 299 || gotPrice == nil || gotPrice.Price != 299 {
+		t.Fatalf("expected catalog default 299, got %d and %+v", updated.Price, gotPrice)
+	}
+}
+
+func TestUpdate_IfMatchMismatch_ReturnsErrPreconditionFailed(t *testing.T) {
+	repo := &repoMock{
+		getByIDFn: func(ctx context.Context, id int64) (*domain.Subscription, error) {
+			return &domain.Subscription{ID: id, Price: 400, Version: 3}, nil
+		},
+	}
+	svc := NewSubscriptionService(repo, newCatalogMock(), nil)
+
+	version := int64(2)
+	_, err := svc.Update(context.Background(), 7, UpdateSubscriptionRequest{IfMatch: &version, EndDate: EndDateNotProvided()})
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("expected ErrPreconditionFailed, got %v", err)
+	}
+}
+
+func TestUpdate_DatesMustKeepPausesInRange(t *testing.T) {
+	start, _ := domain.ParseMonthYear("01-2025")
+	until, _ := domain.ParseMonthYear("04-2025")
+	existing := domain.Subscription{ID: 7, ServiceName: "Netflix", Price: 400, UserID: "60610fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: start, Version: 1}
+	existing.Pauses = []domain.Pause{{From: until.AddDate(0, -1, 0), Until: &until}}
+
+	repo := &repoMock{
+		getByIDFn: func(ctx context.Context, id int64) (*domain.Subscription, error) {
+			s := existing
+			return &s, nil
+		},
+		updateFn: func(ctx context.Context, s domain.Subscription, price *domain.PriceChange) (*domain.Subscription, error) {
+			return &s, nil
+		},
+		discountsFn: func(ctx context.Context, id int64) ([]domain.Discount, error) {
+			return nil, nil
+		},
+	}
+	svc := NewSubscriptionService(repo, newCatalogMock(), nil)
+
+	late, early := "04-2025", "03-2025"
+	tests := []struct {
+		name    string
+		req     UpdateSubscriptionRequest
+		wantErr bool
+	}{
+		{name: "start after pause", req: UpdateSubscriptionRequest{StartDate: &late, EndDate: EndDateNotProvided()}, wantErr: true},
+		{name: "end before pause ends", req: UpdateSubscriptionRequest{EndDate: EndDateSetValue("03-2025")}, wantErr: true},
+		{name: "end after pause", req: UpdateSubscriptionRequest{EndDate: EndDateSetValue("04-2025")}},
+		{name: "start up to pause", req: UpdateSubscriptionRequest{StartDate: &early, EndDate: EndDateNotProvided()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Update(context.Background(), 7, tt.req)
+			if tt.wantErr != errors.Is(err, ErrInvalidInput) || (!tt.wantErr && err != nil) {
+				t.Fatalf("wantErr=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestUpdate_DatesMustKeepDiscountsInRange(t *testing.T) {
+	start, _ := domain.ParseMonthYear("01-2025")
+	from, _ := domain.ParseMonthYear("03-2025")
+	existing := domain.Subscription{ID: 7, ServiceName: "Netflix", Price: 400, UserID: "60610fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: start, Version: 1}
+
+	var listed int
+	repo := &repoMock{
+		getByIDFn: func(ctx context.Context, id int64) (*domain.Subscription, error) {
+			s := existing
+			return &s, nil
+		},
+		updateFn: func(ctx context.Context, s domain.Subscription, price *domain.PriceChange) (*domain.Subscription, error) {
+			return &s, nil
+		},
+		discountsFn: func(ctx context.Context, id int64) ([]domain.Discount, error) {
+			listed++
+			return []domain.Discount{{ID: 1, SubscriptionID: id, Kind: domain.DiscountPercent, Value: 10, From: from}}, nil
+		},
+	}
+	svc := NewSubscriptionService(repo, newCatalogMock(), nil)
+
+	late := "04-2025"
+	tests := []struct {
+		name    string
+		req     UpdateSubscriptionRequest
+		wantErr bool
+	}{
+		{name: "start after discount", req: UpdateSubscriptionRequest{StartDate: &late, EndDate: EndDateNotProvided()}, wantErr: true},
+		{name: "end before discount", req: UpdateSubscriptionRequest{EndDate: EndDateSetValue("02-2025")}, wantErr: true},
+		{name: "end in discount month", req: UpdateSubscriptionRequest{EndDate: EndDateSetValue("03-2025")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Update(context.Background(), 7, tt.req)
+			if tt.wantErr != errors.Is(err, ErrInvalidInput) || (!tt.wantErr && err != nil) {
+				t.Fatalf("wantErr=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// без смены дат скидки не перечитываются
+	listed = 0
+	if _, err := svc.Update(context.Background(), 7, UpdateSubscriptionRequest{AddTags: []string{"video"}, EndDate: EndDateNotProvided()}); err != nil || listed != 0 {
+		t.Fatalf("expected no discount lookup, got %d lookups, %v", listed, err)
+	}
+}
+
+func TestUpdate_AddRemoveTags(t *testing.T) {
+	var saved domain.Subscription
+	repo := &repoMock{
+		getByIDFn: func(ctx context.Context, id int64) (*domain.Subscription, error) {
+			return &domain.Subscription{ID: id, Price: 400, Tags: []string{"cloud", "dev"}}, nil
+		},
+		updateFn: func(ctx context.Context, s domain.Subscription, price *domain.PriceChange) (*domain.Subscription, error) {
+			saved = s
+			return &s, nil
+		},
+	}
+	svc := NewSubscriptionService(repo, newCatalogMock(), nil)
+
+	_, err := svc.Update(context.Background(), 7, UpdateSubscriptionRequest{
+		AddTags:    []string{" Entertainment ", "dev"},
+		RemoveTags: []string{"CLOUD"},
+		EndDate:    EndDateNotProvided(),
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if want := []string{"dev", "entertainment"}; !slices.Equal(saved.Tags, want) {
+		t.Fatalf("expected tags %v, got %v", want, saved.Tags)
+	}
+
+	replace := []string{"x"}
+	_, err = svc.Update(context.Background(), 7, UpdateSubscriptionRequest{
+		Tags:    &replace,
+		AddTags: []string{"y"},
+		EndDate: EndDateNotProvided(),
+	})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+}
+
+// catalogMock — каталог в памяти: Resolve ищет по ключу имени, Ensure заводит новую запись.
+type catalogMock struct {
+	byKey map[string]domain.Service
+}
+
+func newCatalogMock(services ...domain.Service) *catalogMock {
+	m := &catalogMock{byKey: map[string]domain.Service{}}
+	for _, s := range services {
+		m.byKey[domain.ServiceNameKey(s.Name)] = s
+		for _, a := range s.Aliases {
+			m.byKey[domain.ServiceNameKey(a)] = s
+		}
+	}
+	return m
+}
+
+func (m *catalogMock) Create(ctx context.Context, s domain.Service) (int64, error) {
+	panic("not implemented")
+}
+
+func (m *catalogMock) GetByID(ctx context.Context, id int64) (*domain.Service, error) {
+	panic("not implemented")
+}
+
+func (m *catalogMock) Update(ctx context.Context, s domain.Service) (*domain.Service, error) {
+	panic("not implemented")
+}
+
+func (m *catalogMock) Delete(ctx context.Context, id int64) (bool, error) {
+	panic("not implemented")
+}
+
+func (m *catalogMock) List(ctx context.Context, f domain.ServiceFilter) ([]domain.Service, error) {
+	panic("not implemented")
+}
+
+func (m *catalogMock) Resolve(ctx context.Context, name string) (*domain.Service, error) {
+	if s, ok := m.byKey[domain.ServiceNameKey(name)]; ok {
+		return &s, nil
+	}
+	return nil, nil
+}
+
+func (m *catalogMock) Ensure(ctx context.Context, name string) (*domain.Service, error) {
+	if s, ok := m.byKey[domain.ServiceNameKey(name)]; ok {
+		return &s, nil
+	}
+	s := domain.Service{ID: int64(len(m.byKey) + 1), Name: domain.CleanServiceName(name)}
+	m.byKey[domain.ServiceNameKey(name)] = s
+	return &s, nil
+}
+
+func TestCreate_ResolvesAliasAndDefaultPrice(t *testing.T) {
+	price := int64(699)
+	catalog := newCatalogMock(domain.Service{ID: 5, Name: "Netflix", Aliases: []string{"NFLX"}, DefaultPrice: &price})
+
+	var got domain.Subscription
+	repo := &repoMock{
+		createFn: func(ctx context.Context, s domain.Subscription) (int64, error) {
+			got = s
+			return 1, nil
+		},
+	}
+	svc := NewSubscriptionService(repo, catalog, nil)
+
+	_, err := svc.Create(context.Background(), CreateSubscriptionRequest{
+		ServiceName: "  nflx ",
+		UserID:      "60610fee-2bf1-4721-ae6f-7636e79a0cba",
+		StartDate:   "07-2025",
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if got.ServiceID == nil || *got.ServiceID != 5 || got.ServiceName != "Netflix" || got.Price != 699 {
+		t.Fatalf("expected Netflix #5 with default price, got %+v", got)
+	}
+
+	_, err = svc.Create(context.Background(), CreateSubscriptionRequest{
+		ServiceName: "Unknown",
+		UserID:      "60610fee-2bf1-4721-ae6f-7636e79a0cba",
+		StartDate:   "07-2025",
+	})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput without price, got %v", err)
+	}
+}
+
+type idempotencyMock struct {
+	rec       *domain.IdempotencyRecord
+	reserved  bool
+	leaseEnd  time.Time
+	completed *domain.IdempotencyRecord
+}
+
+func (m *idempotencyMock) Reserve(ctx context.Context, key, requestHash string, expiresAt time.Time) (*domain.IdempotencyRecord, bool, error) {
+	m.leaseEnd = expiresAt
+	return m.rec, m.reserved, nil
+}
+
+func (m *idempotencyMock) Complete(ctx context.Context, rec domain.IdempotencyRecord) error {
+	m.completed = &rec
+	return nil
+}
+
+func (m *idempotencyMock) Release(ctx context.Context, key, requestHash string) error { return nil }
+
+func (m *idempotencyMock) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestIdempotencyBegin(t *testing.T) {
+	status := 201
+	done := &domain.IdempotencyRecord{Key: "k", RequestHash: "h1", StatusCode: &status, Response: []byte(`{}`)}
+	pending := &domain.IdempotencyRecord{Key: "k", RequestHash: "h1"}
+
+	tests := []struct {
+		name    string
+		repo    *idempotencyMock
+		hash    string
+		wantRec bool
+		wantErr error
+	}{
+		{name: "new key", repo: &idempotencyMock{reserved: true}, hash: "h1"},
+		{name: "replay", repo: &idempotencyMock{rec: done}, hash: "h1", wantRec: true},
+		{name: "different body", repo: &idempotencyMock{rec: done}, hash: "h2", wantErr: ErrIdempotencyKeyReused},
+		{name: "in progress", repo: &idempotencyMock{rec: pending}, hash: "h1", wantErr: ErrIdempotencyInProgress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewIdempotencyService(tt.repo, 0)
+			rec, err := svc.Begin(context.Background(), "k", tt.hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if (rec != nil) != tt.wantRec {
+				t.Fatalf("expected record=%v, got %+v", tt.wantRec, rec)
+			}
+		})
+	}
+}
+
+func TestIdempotency_LeaseUntilCompleteThenTTL(t *testing.T) {
+	now := time.Date(2025, 11, 20, 15, 0, 0, 0, time.UTC)
+	repo := &idempotencyMock{reserved: true}
+	svc := NewIdempotencyService(repo, time.Hour)
+	svc.now = func() time.Time { return now }
+
+	if _, err := svc.Begin(context.Background(), "k", "h1"); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if want := now.Add(IdempotencyLease); !repo.leaseEnd.Equal(want) {
+		t.Fatalf("expected lease until %s, got %s", want, repo.leaseEnd)
+	}
+
+	headers := map[string]string{"ETag": `"1"`}
+	if err := svc.Complete(context.Background(), "k", "h1", 201, headers, []byte(`{}`)); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	got := repo.completed
+	if got == nil || got.RequestHash != "h1" || *got.StatusCode != 201 || got.Headers["ETag"] != `"1"` || !got.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected completed record %+v", got)
+	}
+}
+
+func TestListPage_NextCursorOnlyWhenMoreRows(t *testing.T) {
+	var gotLimit int
+	repo := &repoMock{
+		listFn: func(ctx context.Context, f domain.ListFilter) ([]domain.Subscription, error) {
+			gotLimit = f.Limit
+			return []domain.Subscription{{ID: 1}, {ID: 2}, {ID: 3}}, nil
+		},
+		countFn: func(ctx context.Context, f domain.ListFilter) (int64, error) {
+			return 3, nil
+		},
+	}
+	svc := NewSubscriptionService(repo, newCatalogMock(), nil)
+
+	page, err := svc.ListPage(context.Background(), domain.ListFilter{Limit: 2}, true)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if gotLimit != 3 {
+		t.Fatalf("expected repo limit 3, got %d", gotLimit)
+	}
+	if len(page.Items) != 2 || page.NextCursor == nil || page.TotalCount == nil || *page.TotalCount != 3 {
+		t.Fatalf("unexpected page %+v", page)
+	}
+	c, err := domain.DecodeCursor(*page.NextCursor)
+	if err != nil || c.ID != 2 {
+		t.Fatalf("expected cursor after id 2, got %+v, %v", c, err)
+	}
+
+	page, err = svc.ListPage(context.Background(), domain.ListFilter{Limit: 3, After: &c}, false)
+	if err != nil || page.NextCursor != nil || page.TotalCount != nil {
+		t.Fatalf("expected last page without total, got %+v, %v", page, err)
+	}
+}
+
+func TestImport_StrictSkipsInsertOnInvalidRow(t *testing.T) {
+	var inserted int
+	repo := &repoMock{
+		batchFn: func(ctx context.Context, subs []domain.Subscription) ([]int64, error) {
+			inserted += len(subs)
+			ids := make([]int64, len(subs))
+			for i := range subs {
+				ids[i] = int64(i + 1)
+			}
+			return ids, nil
+		},
+	}
+	svc := NewSubscriptionService(repo, newCatalogMock(), nil)
+
+	rows := []ImportRow{
+		{Line: 1, Request: CreateSubscriptionRequest{ServiceName: "Netflix", Price: 400, UserID: "60610fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: "07-2025"}},
+		{Line: 2, Request: CreateSubscriptionRequest{ServiceName: "Yandex", Price: 300, UserID: "60610fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: "13-2025"}},
+		// postgres отверг бы такую строку при вставке и откатил бы весь импорт
+		{Line: 3, Request: CreateSubscriptionRequest{ServiceName: "Okko", Price: 200, UserID: "user-3", StartDate: "07-2025"}},
+	}
+
+	res, err := svc.Import(context.Background(), rows, true)
+	if err != nil || res.Imported != 0 || res.Failed != 2 || inserted != 0 {
+		t.Fatalf("strict: expected nothing imported, got %+v, %v", res, err)
+	}
+
+	res, err = svc.Import(context.Background(), rows, false)
+	if err != nil || res.Imported != 1 || res.Failed != 2 || inserted != 1 {
+		t.Fatalf("partial: expected 1 imported, got %+v, %v", res, err)
+	}
+	if res.Rows[0].ID == nil || res.Rows[1].Error == "" || res.Rows[2].Error == "" {
+		t.Fatalf("expected id for line 1 and errors for lines 2 and 3, got %+v", res.Rows)
+	}
+}
+
+func TestPurgeDeleted_CutoffFromServiceClock(t *testing.T) {
+	var cutoff time.Time
+	repo := &repoMock{
+		purgeFn: func(ctx context.Context, olderThan time.Time) (int64, error) {
+			cutoff = olderThan
+			return 0, nil
+		},
+	}
+	svc := NewSubscriptionService(repo, newCatalogMock(), nil)
+	svc.now = func() time.Time { return time.Date(2025, 11, 20, 15, 0, 0, 0, time.UTC) }
+
+	if _, err := svc.PurgeDeleted(context.Background(), 30*24*time.Hour); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if want := time.Date(2025, 10, 21, 15, 0, 0, 0, time.UTC); !cutoff.Equal(want) {
+		t.Fatalf("expected cutoff %s, got %s", want, cutoff)
+	}
+}
+
+func TestCreate_StrictRejectsOverlap(t *testing.T) {
+	end := time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)
+	var created int
+	repo := &repoMock{}
+	// пересечения проверяет репозиторий в транзакции записи; сервис только передаёт режим
+	repo.createFn = func(ctx context.Context, s domain.Subscription) (int64, error) {
+		if repo.rejectOverlaps {
+			other := domain.Subscription{ID: 1, ServiceName: s.ServiceName, UserID: s.UserID, StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), EndDate: &end}
+			return 0, OverlapConflict(s, other)
+		}
+		created++
+		return 2, nil
+	}
+	svc := NewSubscriptionService(repo, newCatalogMock(), nil)
+
+	req := CreateSubscriptionRequest{
+		ServiceName:    "Netflix",
+		Price:          400,
+		UserID:         "60610fee-2bf1-4721-ae6f-7636e79a0cba",
+		StartDate:      "07-2025",
+		RejectOverlaps: true,
+	}
+	_, err := svc.Create(context.Background(), req)
+	if !errors.Is(err, ErrConflict) || created != 0 {
+		t.Fatalf("strict: expected ErrConflict without insert, got %v (created %d)", err, created)
+	}
+	if !strings.Contains(err.Error(), "subscription 1") || !strings.Contains(err.Error(), "07-2025") {
+		t.Fatalf("expected overlapping id and period in error, got %q", err)
+	}
+
+	// по умолчанию пересечения разрешены и не проверяются
+	req.RejectOverlaps = false
+	if _, err := svc.Create(context.Background(), req); err != nil || created != 1 {
+		t.Fatalf("lenient: expected created, got %v (created %d)", err, created)
+	}
+}
+
+// budgetMock хранит бюджеты и превышения в срезах, с уникальностью превышения по бюджету и месяцу.
+type budgetMock struct {
+	budgets []domain.Budget
+	alerts  []domain.BudgetAlert
+	listed  int // сколько раз вызван List
+}
+
+func (m *budgetMock) Create(ctx context.Context, b domain.Budget) (int64, error) {
+	panic("not implemented")
+}
+
+func (m *budgetMock) GetByID(ctx context.Context, id int64) (*domain.Budget, error) {
+	panic("not implemented")
+}
+
+func (m *budgetMock) Update(ctx context.Context, b domain.Budget) (*domain.Budget, error) {
+	panic("not implemented")
+}
+
+func (m *budgetMock) Delete(ctx context.Context, id int64) (bool, error) {
+	panic("not implemented")
+}
+
+func (m *budgetMock) List(ctx context.Context, userID string) ([]domain.Budget, error) {
+	m.listed++
+	var out []domain.Budget
+	for _, b := range m.budgets {
+		if userID == "" || b.UserID == userID {
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
+func (m *budgetMock) AddAlert(ctx context.Context, o domain.BudgetOverrun) (*domain.BudgetAlert, error) {
+	for _, a := range m.alerts {
+		if a.BudgetID == o.BudgetID && a.Month.Equal(o.Month) {
+			return nil, nil
+		}
+	}
+	a := domain.BudgetAlert{ID: int64(len(m.alerts) + 1), BudgetOverrun: o}
+	m.alerts = append(m.alerts, a)
+	return &a, nil
+}
+
+func (m *budgetMock) ListAlerts(ctx context.Context, f domain.BudgetAlertFilter) ([]domain.BudgetAlert, error) {
+	var out []domain.BudgetAlert
+	for _, a := range m.alerts {
+		if f.Pending && a.NotifiedAt != nil {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+func (m *budgetMock) MarkNotified(ctx context.Context, alertID int64, at time.Time) error {
+	m.alerts[alertID-1].NotifiedAt = &at
+	return nil
+}
+
+type notifierMock struct {
+	sent []domain.BudgetAlert
+	err  error
+}
+
+func (m *notifierMock) NotifyBudgetExceeded(ctx context.Context, a domain.BudgetAlert) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, a)
+	return nil
+}
+
+func TestBudgetEvaluate_RecordsOnceAndRetriesDelivery(t *testing.T) {
+	userID := "60610fee-2bf1-4721-ae6f-7636e79a0cba"
+	budgets := &budgetMock{budgets: []domain.Budget{
+		{ID: 1, UserID: userID, Amount: 1000, Currency: "RUB"},
+		{ID: 2, UserID: userID, Amount: 5000, Currency: "RUB"},
+	}}
+
+	var got domain.TotalFilter
+	repo := &repoMock{
+		totalCostFn: func(ctx context.Context, f domain.TotalFilter) (domain.CostTotal, error) {
+			got = f
+			return domain.CostTotal{Gross: 1500, Net: 1500}, nil
+		},
+	}
+	notifier := &notifierMock{err: errors.New("smtp down")}
+	svc := NewBudgetService(budgets, NewSubscriptionService(repo, newCatalogMock(), budgets), notifier)
+	svc.now = func() time.Time { return time.Date(2025, 11, 20, 15, 0, 0, 0, time.UTC) }
+
+	n, err := svc.Evaluate(context.Background())
+	if n != 1 || err == nil {
+		t.Fatalf("expected 1 alert and a delivery error, got %d, %v", n, err)
+	}
+	if domain.FormatMonthYear(got.From) != "11-2025" || got.UserID == nil || *got.UserID != userID {
+		t.Fatalf("expected the current month of the user, got %+v", got)
+	}
+	if len(budgets.alerts) != 1 || budgets.alerts[0].BudgetID != 1 || budgets.alerts[0].Spent != 1500 {
+		t.Fatalf("expected alert for budget 1, got %+v", budgets.alerts)
+	}
+
+	// повторная проверка того же месяца не дублирует превышение, но дослаёт уведомление
+	notifier.err = nil
+	n, err = svc.Evaluate(context.Background())
+	if n != 0 || err != nil {
+		t.Fatalf("expected no new alerts, got %d, %v", n, err)
+	}
+	if len(notifier.sent) != 1 || budgets.alerts[0].NotifiedAt == nil {
+		t.Fatalf("expected the alert to be delivered once, got %+v", notifier.sent)
+	}
+}
+
+func TestCreateWithBudgetCheck_OnlyChargedMonthsOverBudget(t *testing.T) {
+	userID := "60610fee-2bf1-4721-ae6f-7636e79a0cba"
+	tag := "video"
+	budgets := &budgetMock{budgets: []domain.Budget{
+		{ID: 1, UserID: userID, Amount: 1000, Currency: "RUB"},
+		{ID: 2, UserID: userID, Amount: 1000, Currency: "RUB", Tag: &tag},
+	}}
+
+	spent := int64(900)
+	repo := &repoMock{
+		createFn: func(ctx context.Context, s domain.Subscription) (int64, error) {
+			spent = 1200
+			return 1, nil
+		},
+		breakdownFn: func(ctx context.Context, f domain.TotalFilter) ([]domain.MonthlyCost, error) {
+			var out []domain.MonthlyCost
+			for m := f.From; !m.After(f.To); m = domain.NextMonthStartUTC(m) {
+				out = append(out, domain.MonthlyCost{Month: m, Amount: spent})
+			}
+			return out, nil
+		},
+	}
+	svc := NewSubscriptionService(repo, newCatalogMock(), budgets)
+	svc.now = func() time.Time { return time.Date(2025, 11, 20, 15, 0, 0, 0, time.UTC) }
+
+	// квартальная подписка без тега: списания в 01-2026 и 04-2026, до конца в 05-2026
+	end := "05-2026"
+	_, check, err := svc.CreateWithBudgetCheck(context.Background(), CreateSubscriptionRequest{
+		ServiceName:   "Netflix",
+		Price:         300,
+		BillingPeriod: "quarterly",
+		UserID:        userID,
+		StartDate:     "01-2026",
+		EndDate:       &end,
+	})
+	if err != nil || check.Err != nil {
+		t.Fatalf("expected nil, got %v, %v", err, check.Err)
+	}
+	var months []string
+	for _, w := range check.Warnings {
+		if w.BudgetID != 1 || w.Spent != 1200 || w.Limit != 1000 {
+			t.Fatalf("unexpected warning %+v", w)
+		}
+		months = append(months, domain.FormatMonthYear(w.Month))
+	}
+	if !slices.Equal(months, []string{"01-2026", "04-2026"}) {
+		t.Fatalf("expected 01-2026 and 04-2026, got %v", months)
+	}
+}
+
+func TestUpdateWithBudgetCheck_WarnsOnlyWhenChangeAddsToOverrun(t *testing.T) {
+	userID := "60610fee-2bf1-4721-ae6f-7636e79a0cba"
+	budgets := &budgetMock{budgets: []domain.Budget{{ID: 1, UserID: userID, Amount: 1000, Currency: "RUB"}}}
+	start, _ := domain.ParseMonthYear("01-2025")
+
+	var before, after int64
+	var breakdownErr error
+	spent := &before
+	repo := &repoMock{
+		getByIDFn: func(ctx context.Context, id int64) (*domain.Subscription, error) {
+			spent = &before
+			return &domain.Subscription{ID: id, ServiceName: "Netflix", Price: 400, UserID: userID, StartDate: start, Version: 1}, nil
+		},
+		updateFn: func(ctx context.Context, s domain.Subscription, price *domain.PriceChange) (*domain.Subscription, error) {
+			spent = &after
+			return &s, nil
+		},
+		breakdownFn: func(ctx context.Context, f domain.TotalFilter) ([]domain.MonthlyCost, error) {
+			if breakdownErr != nil {
+				return nil, breakdownErr
+			}
+			return []domain.MonthlyCost{{Month: f.From, Amount: *spent}}, nil
+		},
+	}
+	svc := NewSubscriptionService(repo, newCatalogMock(), budgets)
+	svc.now = func() time.Time { return time.Date(2025, 11, 20, 15, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name          string
+		before, after int64
+		wantWarning   bool
+	}{
+		{name: "crosses the limit", before: 900, after: 1100, wantWarning: true},
+		{name: "increases the overrun", before: 1100, after: 1300, wantWarning: true},
+		{name: "unchanged overrun, e.g. tags only", before: 1200, after: 1200},
+		{name: "lowers the overrun", before: 1500, after: 1300},
+		{name: "within budget", before: 500, after: 900},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, after = tt.before, tt.after
+			budgets.listed = 0
+			_, check, err := svc.UpdateWithBudgetCheck(context.Background(), 7, UpdateSubscriptionRequest{AddTags: []string{"video"}, EndDate: EndDateNotProvided()})
+			if err != nil || check.Err != nil {
+				t.Fatalf("expected nil, got %v, %v", err, check.Err)
+			}
+			if got := len(check.Warnings) > 0; got != tt.wantWarning {
+				t.Fatalf("expected warning=%v, got %+v", tt.wantWarning, check.Warnings)
+			}
+			if budgets.listed != 1 {
+				t.Fatalf("expected budgets to be listed once per request, got %d", budgets.listed)
+			}
+		})
+	}
+
+	// правка, не влияющая на стоимость, бюджеты не сверяет
+	breakdownErr = errors.New("breakdown must not be called")
+	name := "Netflix"
+	_, check, err := svc.UpdateWithBudgetCheck(context.Background(), 7, UpdateSubscriptionRequest{ServiceName: &name, EndDate: EndDateNotProvided()})
+	if err != nil || check.Err != nil || check.Warnings != nil {
+		t.Fatalf("expected no budget check, got %+v, %v", check, err)
+	}
+
+	// ошибка сверки не отменяет изменения, но и не выдаётся за пустой список предупреждений
+	breakdownErr = ErrMissingExchangeRate
+	price := int64(500)
+	updated, check, err := svc.UpdateWithBudgetCheck(context.Background(), 7, UpdateSubscriptionRequest{Price: &price, EndDate: EndDateNotProvided()})
+	if err != nil || updated == nil {
+		t.Fatalf("expected update to succeed, got %v", err)
+	}
+	if !errors.Is(check.Err, ErrMissingExchangeRate) || check.Warnings != nil {
+		t.Fatalf("expected failed check, got %+v", check)
+	}
+}
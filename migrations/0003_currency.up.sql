@@ -0,0 +1,12 @@
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'RUB'
+    CHECK (currency ~ '^[A-Z]{3}$');
+
+-- rate: сколько RUB стоит 1 единица currency в данном месяце
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    month       DATE NOT NULL,
+    currency    TEXT NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+    rate        NUMERIC(20, 8) NOT NULL CHECK (rate > 0),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (month, currency)
+    );
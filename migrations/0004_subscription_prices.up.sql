@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS subscription_prices (
+    subscription_id  BIGINT NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    effective_from   DATE NOT NULL,
+    price            BIGINT NOT NULL CHECK (price > 0),
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (subscription_id, effective_from)
+    );
+
+-- текущая цена действует с начала подписки
+INSERT INTO subscription_prices (subscription_id, effective_from, price)
+SELECT id, start_date, price
+FROM subscriptions
+ON CONFLICT DO NOTHING;
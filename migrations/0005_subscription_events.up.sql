@@ -0,0 +1,13 @@
+-- без внешнего ключа: история должна пережить удаление подписки
+CREATE TABLE IF NOT EXISTS subscription_events (
+    id               BIGSERIAL PRIMARY KEY,
+    subscription_id  BIGINT NOT NULL,
+    action           TEXT NOT NULL,
+    before           JSONB NULL,
+    after            JSONB NULL,
+    request_id       TEXT NULL,
+    actor            TEXT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+    );
+
+CREATE INDEX IF NOT EXISTS idx_subscription_events_subscription_id ON subscription_events(subscription_id, id);
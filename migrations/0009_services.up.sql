@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS services (
+    id             BIGSERIAL PRIMARY KEY,
+    name           TEXT NOT NULL,
+    name_key       TEXT NOT NULL,
+    category       TEXT NULL,
+    default_price  BIGINT NULL CHECK (default_price > 0),
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+    );
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_services_name_key ON services(name_key);
+
+CREATE TABLE IF NOT EXISTS service_aliases (
+    alias_key   TEXT PRIMARY KEY,
+    alias       TEXT NOT NULL,
+    service_id  BIGINT NOT NULL REFERENCES services(id) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS idx_service_aliases_service_id ON service_aliases(service_id);
+
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS service_id BIGINT NULL REFERENCES services(id);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_service_id ON subscriptions(service_id);
+
+-- одна запись каталога на каждое имя с точностью до регистра и пробелов,
+-- каноническим становится самое частое написание
+INSERT INTO services (name, name_key)
+SELECT DISTINCT ON (name_key) name, name_key
+FROM (
+    SELECT regexp_replace(btrim(service_name), '\s+', ' ', 'g') AS name,
+           lower(regexp_replace(btrim(service_name), '\s+', ' ', 'g')) AS name_key,
+           count(*) AS n
+    FROM subscriptions
+    GROUP BY 1, 2
+    ) spellings
+ORDER BY name_key, n DESC, name
+ON CONFLICT (name_key) DO NOTHING;
+
+UPDATE subscriptions s
+SET service_id = sv.id,
+    service_name = sv.name
+FROM services sv
+WHERE s.service_id IS NULL
+  AND sv.name_key = lower(regexp_replace(btrim(s.service_name), '\s+', ' ', 'g'));
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS subscription_tags (
+    subscription_id BIGINT NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    tag             TEXT   NOT NULL,
+    PRIMARY KEY (subscription_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_tags_tag ON subscription_tags (tag);
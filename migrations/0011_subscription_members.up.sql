@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS subscription_members (
+    subscription_id BIGINT  NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    user_id         UUID    NOT NULL,
+    weight          INTEGER NOT NULL DEFAULT 1 CHECK (weight > 0),
+    PRIMARY KEY (subscription_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_members_user_id ON subscription_members (user_id);
@@ -0,0 +1,2 @@
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_trial_end_check;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS trial_end;
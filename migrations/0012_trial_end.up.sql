@@ -0,0 +1,5 @@
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS trial_end DATE;
+
+ALTER TABLE subscriptions
+    ADD CONSTRAINT subscriptions_trial_end_check
+    CHECK (trial_end IS NULL OR (trial_end >= start_date AND (end_date IS NULL OR trial_end <= end_date)));
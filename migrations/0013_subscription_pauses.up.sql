@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS subscription_pauses (
+    subscription_id BIGINT NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    paused_from     DATE   NOT NULL,
+    paused_until    DATE   NULL, -- последний приостановленный месяц, NULL = до возобновления
+    PRIMARY KEY (subscription_id, paused_from),
+    CHECK (paused_until IS NULL OR paused_until >= paused_from)
+);
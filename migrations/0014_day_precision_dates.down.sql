@@ -0,0 +1,9 @@
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_trial_end_check;
+
+UPDATE subscriptions
+SET start_date = date_trunc('month', start_date)::date,
+    end_date = date_trunc('month', end_date)::date;
+
+ALTER TABLE subscriptions
+    ADD CONSTRAINT subscriptions_trial_end_check
+    CHECK (trial_end IS NULL OR (trial_end >= start_date AND (end_date IS NULL OR trial_end <= end_date)));
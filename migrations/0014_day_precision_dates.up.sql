@@ -0,0 +1,10 @@
+-- end_date хранит последний день действия подписки, а не первый день последнего месяца
+UPDATE subscriptions
+SET end_date = (date_trunc('month', end_date) + interval '1 month' - interval '1 day')::date
+WHERE end_date IS NOT NULL;
+
+-- trial_end — месяц, а start_date теперь может быть серединой месяца
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_trial_end_check;
+ALTER TABLE subscriptions
+    ADD CONSTRAINT subscriptions_trial_end_check
+    CHECK (trial_end IS NULL OR (trial_end >= date_trunc('month', start_date) AND (end_date IS NULL OR trial_end <= end_date)));
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS subscription_discounts (
+    id              BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT      NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    kind            TEXT        NOT NULL CHECK (kind IN ('percent', 'fixed')),
+    value           BIGINT      NOT NULL CHECK (value > 0),
+    code            TEXT        NULL,
+    from_month      DATE        NOT NULL,
+    until_month     DATE        NULL, -- последний месяц скидки, NULL = бессрочно
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (kind <> 'percent' OR value <= 100),
+    CHECK (until_month IS NULL OR until_month >= from_month)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_discounts_subscription_id ON subscription_discounts (subscription_id);
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS budgets (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     UUID        NOT NULL,
+    amount      BIGINT      NOT NULL CHECK (amount > 0), -- лимит на месяц в currency
+    currency    TEXT        NOT NULL DEFAULT 'RUB',
+    tag         TEXT        NULL, -- NULL = все подписки пользователя
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_budgets_user_id ON budgets (user_id);
+
+CREATE TABLE IF NOT EXISTS budget_alerts (
+    id           BIGSERIAL PRIMARY KEY,
+    budget_id    BIGINT      NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+    user_id      UUID        NOT NULL,
+    month        DATE        NOT NULL,
+    limit_amount BIGINT      NOT NULL,
+    spent        BIGINT      NOT NULL,
+    currency     TEXT        NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    notified_at  TIMESTAMPTZ NULL, -- NULL = уведомление ещё не доставлено
+    UNIQUE (budget_id, month)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budget_alerts_user_id ON budget_alerts (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_budget_alerts_pending ON budget_alerts (id) WHERE notified_at IS NULL;